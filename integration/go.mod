@@ -2,12 +2,16 @@ module github.com/taleeus/sqld/integration
 
 go 1.23.2
 
-replace github.com/taleeus/sqld => ..
+replace (
+	github.com/taleeus/sqld => ..
+	github.com/taleeus/sqld/legacy => ../legacy
+)
 
 require (
 	github.com/jackc/pgx/v5 v5.7.1
 	github.com/jmoiron/sqlx v1.4.0
 	github.com/taleeus/sqld v0.0.0
+	github.com/taleeus/sqld/legacy v0.0.0
 	github.com/testcontainers/testcontainers-go v0.34.0
 	github.com/testcontainers/testcontainers-go/modules/postgres v0.34.0
 )
@@ -0,0 +1,432 @@
+package integration
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/taleeus/sqld"
+	sqld_legacy "github.com/taleeus/sqld/legacy"
+)
+
+func queryLegacy(t *testing.T, op sqld_legacy.SqldFn) pgx.Rows {
+	t.Helper()
+
+	query, vals, err := sqld_legacy.PgPrepareOp(op)()
+	if err != nil {
+		t.Fatalf("query generation failed\nerr: %s", err.Error())
+	}
+
+	args := make([]any, 0, len(vals))
+	for _, val := range vals {
+		args = append(args, val)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		t.Fatalf("query failed\nerr: %s\nquery: %s\nargs: %v", err.Error(), query, args)
+	}
+
+	return rows
+}
+
+func TestUnnestColumn(t *testing.T) {
+	Must(db.Exec(ctx, `
+		INSERT INTO post (title, tags) VALUES
+			('unnest-go', ARRAY['go', 'sql']),
+			('unnest-rust', ARRAY['rust']),
+			('unnest-none', ARRAY[]::TEXT[])
+	`))
+
+	tags := []string{"go", "sql"}
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Just("DISTINCT post.title")),
+		sqld_legacy.From(sqld_legacy.Just("post")),
+		sqld_legacy.Join(sqld_legacy.INNER_JOIN,
+			sqld_legacy.UnnestColumn("post.tags", "tag"),
+			sqld_legacy.Just("TRUE"),
+		),
+		sqld_legacy.Where(
+			sqld_legacy.And(
+				sqld_legacy.Just("post.title LIKE 'unnest-%'"),
+				sqld_legacy.In("tag", &tags),
+			),
+		),
+	))
+
+	titles := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(titles) != 1 || titles[0] != "unnest-go" {
+		t.Fatalf("wrong rows matched by array membership: %v", titles)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(
+			sqld_legacy.PercentileCont(0.5, sqld_legacy.Just("n")),
+			sqld_legacy.PercentileDisc(0.9, sqld_legacy.Just("n")),
+		),
+		sqld_legacy.From(sqld_legacy.Just("generate_series(1, 100) AS n")),
+	))
+
+	var cont float64
+	var disc int
+	Must(pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (any, error) {
+		return nil, row.Scan(&cont, &disc)
+	}))
+
+	if cont != 50.5 || disc != 90 {
+		t.Fatalf("wrong percentiles: cont %v, disc %v", cont, disc)
+	}
+}
+
+func TestRangeOverlaps(t *testing.T) {
+	Must(db.Exec(ctx, `
+		INSERT INTO booking (room, during) VALUES
+			('overlap-morning', tstzrange('2024-01-01 08:00Z', '2024-01-01 12:00Z')),
+			('overlap-evening', tstzrange('2024-01-01 18:00Z', '2024-01-01 22:00Z'))
+	`))
+
+	low := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	high := time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Just("room")),
+		sqld_legacy.From(sqld_legacy.Just("booking")),
+		sqld_legacy.Where(sqld_legacy.RangeOverlaps("during", &low, &high)),
+	))
+
+	rooms := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(rooms) != 1 || rooms[0] != "overlap-morning" {
+		t.Fatalf("wrong overlapping bookings: %v", rooms)
+	}
+}
+
+func TestCountDistinctOn(t *testing.T) {
+	from := sqld_legacy.New(
+		sqld_legacy.From(sqld_legacy.Just("generate_series(1, 100) AS n")),
+		sqld_legacy.Where(sqld_legacy.Just("n <= 50")),
+	)
+
+	rows := queryLegacy(t, sqld_legacy.CountDistinctOn([]string{"n % 7"}, from))
+	approx := Must(pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64]))
+
+	rows = queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Count(sqld_legacy.Just("DISTINCT n % 7"))),
+		from,
+	))
+	exact := Must(pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64]))
+
+	if approx != exact || exact != 7 {
+		t.Fatalf("DISTINCT ON count %d differs from COUNT(DISTINCT) %d", approx, exact)
+	}
+}
+
+func TestLeftJoinFiltered(t *testing.T) {
+	Must(db.Exec(ctx, `
+		WITH p AS (
+			INSERT INTO parent (name) VALUES ('ljf-active'), ('ljf-inactive'), ('ljf-childless')
+			RETURNING id, name
+		)
+		INSERT INTO child (parent_id, name, active)
+		SELECT id, name || '-child', name = 'ljf-active' FROM p WHERE name != 'ljf-childless'
+	`))
+
+	active := true
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Columns("parent.name", "child.name")),
+		sqld_legacy.From(sqld_legacy.Just("parent")),
+		sqld_legacy.LeftJoinFiltered(sqld_legacy.Just("child"),
+			sqld_legacy.ColumnEq("child.parent_id", "parent.id"),
+			sqld_legacy.Eq("child.active", &active),
+		),
+		sqld_legacy.Where(sqld_legacy.Just("parent.name LIKE 'ljf-%'")),
+		sqld_legacy.OrderBy(sqld_legacy.Asc("parent.name")),
+	))
+
+	type result struct {
+		Parent string
+		Child  *string
+	}
+	results := Must(pgx.CollectRows(rows, func(row pgx.CollectableRow) (result, error) {
+		var r result
+		return r, row.Scan(&r.Parent, &r.Child)
+	}))
+
+	if len(results) != 3 {
+		t.Fatalf("left rows should be preserved: %v", results)
+	}
+	for _, r := range results {
+		if (r.Parent == "ljf-active") != (r.Child != nil) {
+			t.Fatalf("only the active child should be joined: %v", results)
+		}
+	}
+}
+
+func TestAntiJoin(t *testing.T) {
+	Must(db.Exec(ctx, `
+		WITH p AS (
+			INSERT INTO parent (name) VALUES ('aj-active'), ('aj-inactive'), ('aj-childless')
+			RETURNING id, name
+		)
+		INSERT INTO child (parent_id, name, active)
+		SELECT id, name || '-child', name = 'aj-active' FROM p WHERE name != 'aj-childless'
+	`))
+
+	active := true
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Just("parent.name")),
+		sqld_legacy.From(sqld_legacy.Just("parent")),
+		sqld_legacy.Where(
+			sqld_legacy.And(
+				sqld_legacy.Just("parent.name LIKE 'aj-%'"),
+				sqld_legacy.AntiJoin[Parent, Child]("id", "parent_id",
+					sqld_legacy.Eq("child.active", &active),
+				),
+			),
+		),
+		sqld_legacy.OrderBy(sqld_legacy.Asc("parent.name")),
+	))
+
+	names := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(names) != 2 || names[0] != "aj-childless" || names[1] != "aj-inactive" {
+		t.Fatalf("wrong parents without active children: %v", names)
+	}
+}
+
+func TestScalarOrDefault(t *testing.T) {
+	name := "scalar-or-default-missing"
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(
+			sqld_legacy.ScalarOrDefault(
+				sqld_legacy.New(
+					sqld_legacy.Select(sqld_legacy.Just("id")),
+					sqld_legacy.From(sqld_legacy.Just("parent")),
+					sqld_legacy.Where(sqld_legacy.Eq("name", &name)),
+				),
+				-1,
+			),
+		),
+	))
+
+	id := Must(pgx.CollectExactlyOneRow(rows, pgx.RowTo[int]))
+	if id != -1 {
+		t.Fatalf("empty subquery should fall back on default: %d", id)
+	}
+}
+
+func TestArraySubquery(t *testing.T) {
+	Must(db.Exec(ctx, `
+		WITH p AS (
+			INSERT INTO parent (name) VALUES ('array-parent') RETURNING id
+		)
+		INSERT INTO child (parent_id, name, active)
+		SELECT id, child_name, TRUE FROM p, unnest(ARRAY['array-b', 'array-a']) AS child_name
+	`))
+
+	name := "array-parent"
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(
+			sqld_legacy.ArraySubquery(
+				sqld_legacy.New(
+					sqld_legacy.Select(sqld_legacy.Just("child.name")),
+					sqld_legacy.From(sqld_legacy.Just("child")),
+					sqld_legacy.Where(sqld_legacy.ColumnEq("child.parent_id", "parent.id")),
+					sqld_legacy.OrderBy(sqld_legacy.Asc("child.name")),
+				),
+			),
+		),
+		sqld_legacy.From(sqld_legacy.Just("parent")),
+		sqld_legacy.Where(sqld_legacy.Eq("parent.name", &name)),
+	))
+
+	children := Must(pgx.CollectExactlyOneRow(rows, pgx.RowTo[[]string]))
+	if len(children) != 2 || children[0] != "array-a" || children[1] != "array-b" {
+		t.Fatalf("wrong children array: %v", children)
+	}
+}
+
+func TestOnConflictDoNothingReturning(t *testing.T) {
+	id := Must(pgx.CollectExactlyOneRow(
+		Must(db.Query(ctx, "INSERT INTO parent (name) VALUES ('conflict') RETURNING id")),
+		pgx.RowTo[int],
+	))
+
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Insert(sqld_legacy.Just("parent"), []string{"id", "name"},
+			[]driver.Value{id, "conflict-again"},
+		),
+		sqld_legacy.OnConflictDoNothing("id"),
+		sqld_legacy.Returning(sqld_legacy.Columns("id")),
+	))
+
+	returned := Must(pgx.CollectRows(rows, pgx.RowTo[int]))
+	if len(returned) != 0 {
+		t.Fatalf("conflicting row should not be returned: %v", returned)
+	}
+}
+
+func TestRunningSum(t *testing.T) {
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(
+			sqld_legacy.RunningSum(sqld_legacy.Just("n"), []string{"n % 2"}, sqld_legacy.Asc("n")),
+		),
+		sqld_legacy.From(sqld_legacy.Just("generate_series(1, 6) AS n")),
+		sqld_legacy.OrderBy(sqld_legacy.Asc("n")),
+	))
+
+	totals := Must(pgx.CollectRows(rows, pgx.RowTo[int64]))
+	expected := []int64{1, 2, 4, 6, 9, 12}
+	if len(totals) != len(expected) {
+		t.Fatalf("wrong running totals: %v", totals)
+	}
+	for i := range expected {
+		if totals[i] != expected[i] {
+			t.Fatalf("wrong running totals: %v", totals)
+		}
+	}
+}
+
+func TestJSONBAgg(t *testing.T) {
+	Must(db.Exec(ctx, `
+		WITH p AS (
+			INSERT INTO parent (name) VALUES ('json-parent') RETURNING id
+		)
+		INSERT INTO child (parent_id, name, active)
+		SELECT id, child_name, TRUE FROM p, unnest(ARRAY['json-b', 'json-a']) AS child_name
+	`))
+
+	name := "json-parent"
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(
+			sqld_legacy.Just("parent.name"),
+			sqld_legacy.JSONBAgg(
+				sqld_legacy.JSONBuildObject(
+					sqld_legacy.JSONField("name", sqld_legacy.Just("child.name")),
+					sqld_legacy.JSONField("active", sqld_legacy.Just("child.active")),
+				),
+				sqld_legacy.Asc("child.name"),
+			),
+		),
+		sqld_legacy.From(sqld_legacy.Just("parent")),
+		sqld_legacy.Join(sqld_legacy.INNER_JOIN,
+			sqld_legacy.Just("child"),
+			sqld_legacy.ColumnEq("child.parent_id", "parent.id"),
+		),
+		sqld_legacy.Where(sqld_legacy.Eq("parent.name", &name)),
+		sqld_legacy.GroupBy(sqld_legacy.Just("parent.name")),
+	))
+
+	type child struct {
+		Name   string `json:"name"`
+		Active bool   `json:"active"`
+	}
+	var parent string
+	var children []child
+	Must(pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (any, error) {
+		var raw []byte
+		if err := row.Scan(&parent, &raw); err != nil {
+			return nil, err
+		}
+
+		return nil, json.Unmarshal(raw, &children)
+	}))
+
+	if parent != name || len(children) != 2 || children[0].Name != "json-a" || !children[0].Active || children[1].Name != "json-b" {
+		t.Fatalf("wrong nested children: %s %v", parent, children)
+	}
+}
+
+func TestFromNamed(t *testing.T) {
+	Must(db.Exec(ctx, "INSERT INTO parent (name) VALUES ('bridge-a'), ('bridge-b'), ('bridge-c')"))
+
+	params := make(sqld.Params)
+	filter := sqld.Or(
+		sqld.IfNotZero("bridge-a", &params, sqld.Eq("name")),
+		sqld.IfNotZero(sqld.FmtEndsWith("-c"), &params, sqld.Like("name")),
+	)
+
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Just("name")),
+		sqld_legacy.From(sqld_legacy.Just("parent")),
+		sqld_legacy.Where(
+			sqld_legacy.And(
+				sqld_legacy.Just("name LIKE 'bridge-%'"),
+				sqld_legacy.FromNamed(filter, sqld_legacy.Params(params)),
+			),
+		),
+		sqld_legacy.OrderBy(sqld_legacy.Asc("name")),
+	))
+
+	names := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(names) != 2 || names[0] != "bridge-a" || names[1] != "bridge-c" {
+		t.Fatalf("wrong rows from mixed query: %v", names)
+	}
+}
+
+func TestFromNamedIn(t *testing.T) {
+	Must(db.Exec(ctx, "INSERT INTO parent (name) VALUES ('bridge-in-a'), ('bridge-in-b'), ('bridge-in-c')"))
+
+	params := make(sqld.Params)
+	filter := sqld.IfNotEmpty([]string{"bridge-in-a", "bridge-in-c"}, &params, sqld.In("name"))
+
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Just("name")),
+		sqld_legacy.From(sqld_legacy.Just("parent")),
+		sqld_legacy.Where(sqld_legacy.FromNamed(filter, sqld_legacy.Params(params))),
+		sqld_legacy.OrderBy(sqld_legacy.Asc("name")),
+	))
+
+	names := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(names) != 2 || names[0] != "bridge-in-a" || names[1] != "bridge-in-c" {
+		t.Fatalf("wrong rows from bridged IN: %v", names)
+	}
+}
+
+func TestArrayContains(t *testing.T) {
+	Must(db.Exec(ctx, `
+		INSERT INTO post (title, tags) VALUES
+			('contains-go-sql', ARRAY['go', 'sql', 'postgres']),
+			('contains-go', ARRAY['go'])
+	`))
+
+	tags := []string{"go", "sql"}
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Columns("post.title")),
+		sqld_legacy.From(sqld_legacy.Just("post")),
+		sqld_legacy.Where(
+			sqld_legacy.And(
+				sqld_legacy.Just("post.title LIKE 'contains-%'"),
+				sqld_legacy.ArrayContains("post.tags", tags),
+			),
+		),
+	))
+
+	titles := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(titles) != 1 || titles[0] != "contains-go-sql" {
+		t.Fatalf("wrong titles: %v", titles)
+	}
+}
+
+func TestEqAny(t *testing.T) {
+	Must(db.Exec(ctx, `
+		INSERT INTO post (title, tags) VALUES
+			('any-go', ARRAY['go']),
+			('any-rust', ARRAY['rust']),
+			('any-zig', ARRAY['zig'])
+	`))
+
+	titles := []string{"any-go", "any-zig"}
+	rows := queryLegacy(t, sqld_legacy.New(
+		sqld_legacy.Select(sqld_legacy.Columns("post.title")),
+		sqld_legacy.From(sqld_legacy.Just("post")),
+		sqld_legacy.Where(sqld_legacy.EqAny("post.title", titles)),
+		sqld_legacy.OrderBy(sqld_legacy.Asc("post.title")),
+	))
+
+	found := Must(pgx.CollectRows(rows, pgx.RowTo[string]))
+	if len(found) != 2 || found[0] != "any-go" || found[1] != "any-zig" {
+		t.Fatalf("wrong titles: %v", found)
+	}
+}
@@ -7,3 +7,23 @@ type Model struct {
 	Name      string
 	CreatedAt time.Time
 }
+
+type Parent struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func (Parent) TableName() string {
+	return "parent"
+}
+
+type Child struct {
+	ID       int    `db:"id"`
+	ParentID int    `db:"parent_id"`
+	Name     string `db:"name"`
+	Active   bool   `db:"active"`
+}
+
+func (Child) TableName() string {
+	return "child"
+}
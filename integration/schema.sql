@@ -3,3 +3,27 @@ CREATE TABLE IF NOT EXISTS model (
     name        TEXT,
     created_at  TIMESTAMP   DEFAULT NOW()
 );
+
+CREATE TABLE IF NOT EXISTS post (
+    id      SERIAL      PRIMARY KEY,
+    title   TEXT,
+    tags    TEXT[]
+);
+
+CREATE TABLE IF NOT EXISTS booking (
+    id      SERIAL      PRIMARY KEY,
+    room    TEXT,
+    during  TSTZRANGE
+);
+
+CREATE TABLE IF NOT EXISTS parent (
+    id      SERIAL      PRIMARY KEY,
+    name    TEXT
+);
+
+CREATE TABLE IF NOT EXISTS child (
+    id          SERIAL      PRIMARY KEY,
+    parent_id   INT         REFERENCES parent(id),
+    name        TEXT,
+    active      BOOLEAN
+);
@@ -4,10 +4,29 @@ import (
 	"database/sql/driver"
 )
 
+// NoOp is an operator that returns an empty string, without values.
+// Empty operators are dropped from the enclosing clauses (e.g. `Select()`, `Where()`, `And()`),
+// and a clause left with no operators is empty too.
 func NoOp() (string, []driver.Value, error) {
 	return "", nil, nil
 }
 
+// Skip returns `NoOp`, to explicitly skip a branch
+func Skip() SqldFn {
+	return NoOp
+}
+
+// IfTrue returns the operator if the condition is true, `NoOp` otherwise.
+//
+//	sqld.IfTrue(filters.OnlyActive, sqld.IsTrue("active"))
+func IfTrue(cond bool, op SqldFn) SqldFn {
+	if cond {
+		return op
+	}
+
+	return NoOp
+}
+
 func IfElse(pred func() bool, trueFn SqldFn, falseFn SqldFn) SqldFn {
 	if pred() {
 		return trueFn
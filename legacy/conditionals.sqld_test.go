@@ -0,0 +1,31 @@
+package sqld_legacy
+
+import "testing"
+
+func TestIfTrue(t *testing.T) {
+	for _, onlyActive := range []bool{true, false} {
+		s, _, err := New(
+			Select(Columns("id")),
+			From(Just("users")),
+			Where(And(
+				IfTrue(onlyActive, IsTrue("active")),
+				Skip(),
+			)),
+		)()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "SELECT\n\tid\nFROM users\n\n"
+		if onlyActive {
+			expected = "SELECT\n\tid\nFROM users\nWHERE\n\t(active IS TRUE\n)\n\n"
+		}
+		if s != expected {
+			t.Fatalf("wrong gated query (%v): %q", onlyActive, s)
+		}
+	}
+
+	if s, vals, err := Skip()(); s != "" || vals != nil || err != nil {
+		t.Fatalf("skip should be a no-op, got %q %v %v", s, vals, err)
+	}
+}
@@ -0,0 +1,98 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// With builds a callback that returns a WITH statement, naming the provided query.
+//
+//	sqld.New(
+//		sqld.With("paid", sqld.New(
+//			sqld.Select(sqld.AllWildcard()),
+//			sqld.From(sqld.Just("orders")),
+//			sqld.Where(sqld.Eq("status", &status)),
+//		)),
+//		sqld.Select(sqld.Count(sqld.AllWildcard())),
+//		sqld.From(sqld.Just("paid")),
+//	)
+func With(name string, query SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := query()
+		if err != nil {
+			return "", nil, fmt.Errorf("with (%s): %w", name, err)
+		}
+
+		return "WITH " + name + " AS (\n" + s + "\n)", vals, nil
+	}
+}
+
+// WithMaterialized builds a callback that returns a WITH statement with a
+// MATERIALIZED or NOT MATERIALIZED hint for the planner.
+// Returns `ErrUnsupportedDialect` if the current dialect is not `Postgres`.
+func WithMaterialized(name string, query SqldFn, materialized bool) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if dialect != Postgres {
+			return "", nil, fmt.Errorf("with materialized (%s): %w", dialect, ErrUnsupportedDialect)
+		}
+
+		s, vals, err := query()
+		if err != nil {
+			return "", nil, fmt.Errorf("with (%s): %w", name, err)
+		}
+
+		hint := "MATERIALIZED"
+		if !materialized {
+			hint = "NOT MATERIALIZED"
+		}
+
+		return "WITH " + name + " AS " + hint + " (\n" + s + "\n)", vals, nil
+	}
+}
+
+// RecursiveCTE builds a callback that returns a WITH RECURSIVE statement, naming the union of
+// the base query and the recursive one, which references the CTE by name.
+// The columns are optional. Returns `ErrNoOps` if one of the queries is empty.
+//
+//	sqld.New(
+//		sqld.RecursiveCTE("tree", []string{"id", "parent_id"},
+//			sqld.New(
+//				sqld.Select(sqld.Columns("id", "parent_id")),
+//				sqld.From(sqld.Just("categories")),
+//				sqld.Where(sqld.Eq("id", &rootID)),
+//			),
+//			sqld.New(
+//				sqld.Select(sqld.Columns("c.id", "c.parent_id")),
+//				sqld.From(sqld.Just("categories c")),
+//				sqld.Join(sqld.INNER_JOIN, sqld.Just("tree t"), sqld.ColumnEq("c.parent_id", "t.id")),
+//			),
+//		),
+//		sqld.Select(sqld.AllWildcard()),
+//		sqld.From(sqld.Just("tree")),
+//	)
+func RecursiveCTE(name string, columns []string, base SqldFn, recursive SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		b, vals, err := base()
+		if err != nil {
+			return "", nil, fmt.Errorf("with recursive (%s): %w", name, err)
+		}
+
+		r, recursiveVals, err := recursive()
+		if err != nil {
+			return "", nil, fmt.Errorf("with recursive (%s): %w", name, err)
+		}
+
+		if b == "" || r == "" {
+			return "", nil, fmt.Errorf("with recursive (%s): %w", name, ErrNoOps)
+		}
+
+		header := name
+		if len(columns) != 0 {
+			header += "(" + strings.Join(columns, ", ") + ")"
+		}
+
+		return "WITH RECURSIVE " + header + " AS (\n" + b + "\nUNION ALL\n" + r + "\n)", append(slices.Clip(vals), recursiveVals...), nil
+	}
+}
@@ -0,0 +1,91 @@
+package sqld_legacy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithMaterialized(t *testing.T) {
+	status := "paid"
+	paid := New(
+		Select(AllWildcard()),
+		From(Just("orders")),
+		Where(Eq("status", &status)),
+	)
+	inner := "SELECT\n\t*\nFROM orders\nWHERE\n\tstatus = ?\n\n"
+
+	s, vals, err := WithMaterialized("paid", paid, true)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "WITH paid AS MATERIALIZED (\n"+inner+"\n)" || len(vals) != 1 {
+		t.Fatalf("wrong materialized CTE: %q %v", s, vals)
+	}
+
+	s, _, err = WithMaterialized("paid", paid, false)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "WITH paid AS NOT MATERIALIZED (\n"+inner+"\n)" {
+		t.Fatalf("wrong not materialized CTE: %q", s)
+	}
+
+	SetDialect(MySQL)
+	defer SetDialect(Postgres)
+	if _, _, err = WithMaterialized("paid", paid, true)(); !errors.Is(err, ErrUnsupportedDialect) {
+		t.Fatalf("expected unsupported dialect error, got %v", err)
+	}
+}
+
+func TestRecursiveCTE(t *testing.T) {
+	s, vals, err := New(
+		RecursiveCTE("series", []string{"n"},
+			Select(Val(1)),
+			New(
+				Select(Just("n + 1")),
+				From(Just("series")),
+				Where(Raw("n < ?", 10)),
+			),
+		),
+		Select(Just("n")),
+		From(Just("series")),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "WITH RECURSIVE series(n) AS (\nSELECT\n\t?\nUNION ALL\nSELECT\n\tn + 1\nFROM series\nWHERE\n\tn < ?\n\n\n)\nSELECT\n\tn\nFROM series\n"
+	if s != expected {
+		t.Fatalf("wrong recursive CTE:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 2 || vals[0] != 1 || vals[1] != 10 {
+		t.Fatalf("values of both queries should flow through: %v", vals)
+	}
+
+	s, _, err = RecursiveCTE("series", nil, Select(Val(1)), Select(Just("n + 1")))()
+	if err != nil || !strings.HasPrefix(s, "WITH RECURSIVE series AS (\n") {
+		t.Fatalf("columns should be optional: %q %v", s, err)
+	}
+
+	if _, _, err = RecursiveCTE("series", nil, Select(Val(1)), NoOp)(); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no ops error, got %v", err)
+	}
+}
+
+func TestRecursiveCTERerun(t *testing.T) {
+	cte := RecursiveCTE("tree", []string{"id"}, Select(Val(1)), Select(Just("id + 1")))
+
+	first, _, err := cte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := cte()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second || !strings.HasPrefix(second, "WITH RECURSIVE tree(id) AS (\n") {
+		t.Fatalf("the callback should render the same query on every run:\n%q\n%q", first, second)
+	}
+}
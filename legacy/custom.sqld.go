@@ -0,0 +1,87 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sync"
+)
+
+// OperatorFunc renders a custom operator, receiving the rendered arguments and all their values (in order)
+type OperatorFunc func(args []string, vals []driver.Value) (string, []driver.Value, error)
+
+var (
+	operatorsMu sync.RWMutex
+	operators   = make(map[string]map[Dialect]OperatorFunc)
+)
+
+// RegisterOperator registers a custom operator renderer, to be invoked with `Custom()`.
+// If dialects are provided, the renderer is used only when one of them is the current dialect;
+// otherwise it's used as fallback for every dialect.
+//
+//	sqld.RegisterOperator("st_dwithin", func(args []string, vals []driver.Value) (string, []driver.Value, error) {
+//		return "ST_DWithin(" + strings.Join(args, ", ") + ")", vals, nil
+//	}, sqld.Postgres)
+func RegisterOperator(name string, fn OperatorFunc, dialects ...Dialect) {
+	operatorsMu.Lock()
+	defer operatorsMu.Unlock()
+
+	if operators[name] == nil {
+		operators[name] = make(map[Dialect]OperatorFunc)
+	}
+
+	if len(dialects) == 0 {
+		operators[name][""] = fn
+		return
+	}
+
+	for _, d := range dialects {
+		operators[name][d] = fn
+	}
+}
+
+// lookupOperator returns the renderer registered for the current dialect, or the fallback one
+func lookupOperator(name string) (OperatorFunc, bool) {
+	operatorsMu.RLock()
+	defer operatorsMu.RUnlock()
+
+	if fn, ok := operators[name][dialect]; ok {
+		return fn, true
+	}
+
+	fn, ok := operators[name][""]
+	return fn, ok
+}
+
+// Custom builds a callback that renders the arguments with the operator registered with `RegisterOperator()`.
+// Returns `ErrUnknownOperator` if no renderer is registered for the current dialect.
+//
+//	sqld.Custom("st_dwithin", sqld.Just("shops.location"), sqld.Val(point), sqld.Val(radius))
+func Custom(name string, args ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		fn, ok := lookupOperator(name)
+		if !ok {
+			return "", nil, fmt.Errorf("%s (%s): %w", name, dialect, ErrUnknownOperator)
+		}
+
+		rendered, vals := make([]string, 0, len(args)), make([]driver.Value, 0)
+		for _, arg := range args {
+			s, argVals, err := arg()
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			rendered = append(rendered, s)
+
+			if len(argVals) != 0 {
+				vals = append(vals, argVals...)
+			}
+		}
+
+		s, vals, err := fn(rendered, vals)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		return s, vals, nil
+	}
+}
@@ -0,0 +1,31 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCustom(t *testing.T) {
+	RegisterOperator("st_dwithin", func(args []string, vals []driver.Value) (string, []driver.Value, error) {
+		return "ST_DWithin(" + strings.Join(args, ", ") + ")", vals, nil
+	}, Postgres)
+
+	s, vals, err := Where(Custom("st_dwithin", Just("shops.location"), Val("POINT(0 0)"), Val(100)))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "WHERE\n\tST_DWithin(shops.location, ?, ?)\n" {
+		t.Fatalf("wrong custom operator: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != "POINT(0 0)" || vals[1] != 100 {
+		t.Fatalf("wrong custom operator values: %v", vals)
+	}
+
+	SetDialect(MySQL)
+	defer SetDialect(Postgres)
+	if _, _, err = Custom("st_dwithin", Just("shops.location"))(); !errors.Is(err, ErrUnknownOperator) {
+		t.Fatalf("expected unknown operator error for other dialects, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedDialect = errors.New("operator not supported by dialect")
+
+// Dialect is the SQL flavour targeted by dialect-aware operators
+type Dialect string
+
+const (
+	Postgres  Dialect = "postgres"
+	MySQL     Dialect = "mysql"
+	SQLite    Dialect = "sqlite"
+	SQLServer Dialect = "sqlserver"
+	Oracle    Dialect = "oracle"
+)
+
+var dialect = Postgres
+
+// SetDialect sets the dialect used by dialect-aware operators (`Postgres` by default).
+// Set it once at startup: it's not safe to change it while queries are being built.
+func SetDialect(d Dialect) {
+	dialect = d
+}
+
+// CurrentDialect returns the dialect used by dialect-aware operators
+func CurrentDialect() Dialect {
+	return dialect
+}
+
+// numbered reports whether the dialect uses numbered placeholders
+func (d Dialect) numbered() bool {
+	return d == Postgres || d == SQLServer || d == Oracle
+}
+
+// placeholder returns the n-th placeholder (starting from 1) of the dialect
+func (d Dialect) placeholder(n int) string {
+	switch d {
+	case Postgres:
+		return "$" + strconv.Itoa(n)
+	case SQLServer:
+		return "@p" + strconv.Itoa(n)
+	case Oracle:
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// Rebind swaps all ? placeholders with the dialect ones:
+// `$N` for `Postgres`, `@pN` for `SQLServer`, `:N` for `Oracle`.
+// `MySQL` and `SQLite` already use ? placeholders, so the query is returned untouched.
+func (d Dialect) Rebind(query string) string {
+	return replacePlaceholders(query, -1, func(i int) string {
+		return d.placeholder(i + 1)
+	})
+}
+
+// Build runs the operator and rebinds the resulting query for the provided dialect.
+// Every SharedArg is bound once for dialects with numbered placeholders.
+//
+//	query, args, err := sqld.Build(sqld.New(...), sqld.SQLServer)
+func Build(op SqldFn, d Dialect) (string, []driver.Value, error) {
+	query, vals, err := op()
+	if err != nil {
+		return "", nil, err
+	}
+
+	query, vals = d.prepare(query, vals)
+	return query, vals, nil
+}
+
+// QuoteIdent quotes the identifier for the provided dialect, so that it can be safely interpolated:
+// double quotes for `Postgres` and `SQLite`, backticks for `MySQL`.
+// Embedded quote characters are escaped by doubling them.
+//
+// The identifier is quoted as a whole: `table.column` is treated as a single name.
+func QuoteIdent(d Dialect, identifier string) string {
+	quote := `"`
+	if d == MySQL {
+		quote = "`"
+	}
+
+	return quote + strings.ReplaceAll(identifier, quote, quote+quote) + quote
+}
+
+// Quote quotes the identifier for the current dialect. See `QuoteIdent()`
+func Quote(identifier string) string {
+	return QuoteIdent(dialect, identifier)
+}
+
+// QuotedColumns is a variant of `Columns()` that quotes every column
+func QuotedColumns(columns ...string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		quoted := make([]string, 0, len(columns))
+		for _, column := range columns {
+			quoted = append(quoted, Quote(column))
+		}
+
+		return Columns(quoted...)()
+	}
+}
+
+// QuotedFrom is a variant of `From()` that quotes the table name
+func QuotedFrom(table string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return From(Just(Quote(table)))()
+	}
+}
+
+// Concat builds a callback that concatenates the expressions, with their values in order.
+// Renders `a || b`, or `CONCAT(a, b)` if the current dialect is `MySQL` or `SQLServer`.
+// Empty operators are skipped.
+//
+//	sqld.Concat(sqld.Just("first_name"), sqld.Val(" "), sqld.Just("last_name"))
+func Concat(ops ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(ops) == 0 {
+			return "", nil, fmt.Errorf("concat: %w", ErrNoOps)
+		}
+
+		args, size, vals, err := runOps(ops)
+		if err != nil {
+			return "", nil, fmt.Errorf("concat: %w", err)
+		}
+
+		if len(args) == 0 {
+			return "", nil, nil
+		}
+
+		if dialect == MySQL || dialect == SQLServer {
+			return joinParts("CONCAT(", args, size, ", ", ")"), vals, nil
+		}
+
+		return joinParts("", args, size, " || ", ""), vals, nil
+	}
+}
@@ -0,0 +1,110 @@
+package sqld_legacy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	if q := QuoteIdent(Postgres, `we"ird`); q != `"we""ird"` {
+		t.Fatalf("wrong postgres quoting: %s", q)
+	}
+	if q := QuoteIdent(SQLite, "name; DROP TABLE users"); q != `"name; DROP TABLE users"` {
+		t.Fatalf("wrong sqlite quoting: %s", q)
+	}
+	if q := QuoteIdent(MySQL, "we`ird"); q != "`we``ird`" {
+		t.Fatalf("wrong mysql quoting: %s", q)
+	}
+}
+
+func TestQuoted(t *testing.T) {
+	query := New(Select(QuotedColumns("name", `sort"by`)), QuotedFrom("users"))
+
+	s, _, err := query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "SELECT\n\t\"name\",\n\t\"sort\"\"by\"\nFROM \"users\"\n" {
+		t.Fatalf("wrong quoted query: %q", s)
+	}
+
+	SetDialect(MySQL)
+	defer SetDialect(Postgres)
+
+	s, _, err = query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "SELECT\n\t`name`,\n\t`sort\"by`\nFROM `users`\n" {
+		t.Fatalf("wrong quoted query after dialect switch: %q", s)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE note = '?' AND a = ? AND b IN (?, ?)"
+	for d, expected := range map[Dialect]string{
+		Postgres:  "SELECT * FROM t WHERE note = '?' AND a = $1 AND b IN ($2, $3)",
+		MySQL:     query,
+		SQLite:    query,
+		SQLServer: "SELECT * FROM t WHERE note = '?' AND a = @p1 AND b IN (@p2, @p3)",
+		Oracle:    "SELECT * FROM t WHERE note = '?' AND a = :1 AND b IN (:2, :3)",
+	} {
+		if rebound := d.Rebind(query); rebound != expected {
+			t.Fatalf("wrong %s rebind:\n%q\n%q", d, rebound, expected)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	name := "test"
+	since := Arg(42)
+	query := Where(And(Eq("name", &name), Eq("a", since), Eq("b", since)))
+
+	s, vals, err := Build(query, SQLServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "WHERE\n\t(name = @p1\nAND a = @p2\nAND b = @p2\n)\n" || len(vals) != 2 || vals[1] != 42 {
+		t.Fatalf("wrong sqlserver build: %q %v", s, vals)
+	}
+
+	s, vals, err = Build(query, MySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "WHERE\n\t(name = ?\nAND a = ?\nAND b = ?\n)\n" || len(vals) != 3 || vals[1] != 42 || vals[2] != 42 {
+		t.Fatalf("wrong mysql build: %q %v", s, vals)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	query := Concat(Just("first_name"), Val(" "), Just("last_name"), Val("!"))
+
+	s, vals, err := query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "first_name || ? || last_name || ?" {
+		t.Fatalf("wrong || concatenation: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != " " || vals[1] != "!" {
+		t.Fatalf("values should follow the operands order: %v", vals)
+	}
+
+	defer SetDialect(Postgres)
+	for _, d := range []Dialect{MySQL, SQLServer} {
+		SetDialect(d)
+
+		s, vals, err = query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "CONCAT(first_name, ?, last_name, ?)" || len(vals) != 2 || vals[0] != " " {
+			t.Fatalf("wrong CONCAT for %s: %q %v", d, s, vals)
+		}
+	}
+
+	if _, _, err := Concat()(); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no operators error, got %v", err)
+	}
+}
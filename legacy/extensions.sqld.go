@@ -1,42 +1,288 @@
 package sqld_legacy
 
 import (
+	"database/sql/driver"
 	"fmt"
 	"reflect"
 	"slices"
+	"strings"
+	"sync"
 )
 
 type Model interface {
 	TableName() string
 }
 
-// TableColumns extracts a list of columns from a `Model`, using sqlx `db` tags
+// modelField is a struct field mapped to a table column
+type modelField struct {
+	field  reflect.StructField
+	column string
+}
+
+var columnTag = "db"
+
+// SetColumnTag sets the struct tag used to map model fields to columns (sqlx `db` by default).
+// Set it once at startup: it's not safe to change it while queries are being built.
+func SetColumnTag(tag string) {
+	columnTag = tag
+}
+
+// modelFields extracts the fields of a `Model` mapped to table columns, using the column tag
 // and falling back on field names
-func TableColumns[M Model]() []string {
-	var model M
-	columns := make([]string, 0)
+func modelFields[M Model]() []modelField {
+	return modelFieldsTag[M](columnTag)
+}
+
+// modelFieldsTag is like `modelFields()`, using the provided struct tag.
+// Pointer models (with pointer receivers) are dereferenced, and models that are not structs have no fields
+func modelFieldsTag[M Model](tag string) []modelField {
+	typ := reflect.TypeOf((*M)(nil)).Elem()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return typeFields(typ, tag)
+}
+
+type fieldsKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// fieldsCache holds the fields extracted by `typeFields()`, so that reflection runs once per type and tag
+var fieldsCache sync.Map
+
+// typeFields is a cached version of `structFields()`. The returned slice must not be modified
+func typeFields(typ reflect.Type, tag string) []modelField {
+	key := fieldsKey{typ: typ, tag: tag}
+	if fields, ok := fieldsCache.Load(key); ok {
+		return fields.([]modelField)
+	}
+
+	fields, _ := fieldsCache.LoadOrStore(key, structFields(typ, tag, nil))
+	return fields.([]modelField)
+}
+
+// structFields extracts the fields of a struct type mapped to table columns by the provided tag.
+// Untagged embedded structs are flattened, and their fields index is relative to the outer struct.
+// Unexported fields and fields tagged with "-" are skipped
+func structFields(typ reflect.Type, tag string, index []int) []modelField {
+	fields := make([]modelField, 0, typ.NumField())
 
-	typ := reflect.TypeOf(model)
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
+		field.Index = append(slices.Clone(index), field.Index...)
+
+		// options like `json:"name,omitempty"` are ignored
+		column, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if column == "-" {
+			continue
+		}
+
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && column == "" && embedded.Kind() == reflect.Struct {
+			fields = append(fields, structFields(embedded, tag, field.Index)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
 
-		column := field.Tag.Get("db")
 		if column == "" {
 			column = field.Name
 		}
 
-		columns = append(columns, model.TableName()+"."+column)
+		fields = append(fields, modelField{field: field, column: column})
+	}
+
+	return fields
+}
+
+// TableColumns extracts a list of columns from a `Model`, using the column tag (sqlx `db` by default)
+// and falling back on field names
+func TableColumns[M Model]() []string {
+	return TableColumnsTag[M](columnTag)
+}
+
+// TableColumnsTag is like `TableColumns()`, using the provided struct tag
+//
+//	sqld.TableColumnsTag[Pizza]("json")
+func TableColumnsTag[M Model](tag string) []string {
+	var model M
+	columns := make([]string, 0)
+
+	for _, f := range modelFieldsTag[M](tag) {
+		columns = append(columns, model.TableName()+"."+f.column)
+	}
+
+	return columns
+}
+
+// TableColumnsExcept is like `TableColumns()`, leaving out the provided columns.
+// Panics if a column is not present in the model
+func TableColumnsExcept[M Model](exclude ...string) []string {
+	columns, err := TableColumnsExceptErr[M](exclude...)
+	if err != nil {
+		panic(err)
 	}
 
 	return columns
 }
 
+// TableColumnsExceptErr is like `TableColumns()`, leaving out the provided columns.
+// Returns error if a column is not present in the model
+func TableColumnsExceptErr[M Model](exclude ...string) ([]string, error) {
+	return selectColumns[M](exclude, false)
+}
+
+// TableColumnsOnly is like `TableColumns()`, keeping only the provided columns in declaration order.
+// Panics if a column is not present in the model
+func TableColumnsOnly[M Model](only ...string) []string {
+	columns, err := TableColumnsOnlyErr[M](only...)
+	if err != nil {
+		panic(err)
+	}
+
+	return columns
+}
+
+// TableColumnsOnlyErr is like `TableColumns()`, keeping only the provided columns in declaration order.
+// Returns error if a column is not present in the model
+func TableColumnsOnlyErr[M Model](only ...string) ([]string, error) {
+	return selectColumns[M](only, true)
+}
+
+// selectColumns returns the qualified model columns that are (or aren't, if keep is false)
+// in the provided ones, which can be qualified with `Model.TableName()`
+func selectColumns[M Model](selected []string, keep bool) ([]string, error) {
+	var model M
+	fields := modelFields[M]()
+
+	names := make([]string, 0, len(selected))
+	for _, column := range selected {
+		column = strings.TrimPrefix(column, model.TableName()+".")
+		if !slices.ContainsFunc(fields, func(f modelField) bool { return f.column == column }) {
+			return nil, fmt.Errorf("column %s (model %T): %w", column, model, ErrColumnNotInModel)
+		}
+
+		names = append(names, column)
+	}
+
+	columns := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if slices.Contains(names, f.column) == keep {
+			columns = append(columns, model.TableName()+"."+f.column)
+		}
+	}
+
+	return columns, nil
+}
+
+// StructValues extracts the column values of a struct (or a pointer to struct), keyed like `TableColumns()`
+// but without the table name. Pairs with `sqlx.Named()` to run inserts and updates.
+//
+//	params, err := sqld.StructValues(pizza)
+//	query, args, err := sqlx.Named("INSERT INTO pizzas (name, price) VALUES (:name, :price)", params)
+func StructValues(model any) (Params, error) {
+	val := reflect.ValueOf(model)
+	for val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return nil, fmt.Errorf("struct values: %w", ErrNilVal)
+		}
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return nil, fmt.Errorf("struct values: %w", ErrNilVal)
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("struct values (%T): %w", model, ErrNotStruct)
+	}
+
+	fields := typeFields(val.Type(), columnTag)
+	params := make(Params, len(fields))
+	for _, f := range fields {
+		fieldVal, err := val.FieldByIndexErr(f.field.Index)
+		if err != nil {
+			// the field is promoted from a nil embedded pointer
+			params[f.column] = nil
+			continue
+		}
+
+		params[f.column] = fieldVal.Interface()
+	}
+
+	return params, nil
+}
+
+// InsertFromModel builds a named INSERT statement for the model, ready for `sqlx.Named()`,
+// with a column for each field mapped by `StructValues()`. The omitted columns (e.g. an auto-increment id)
+// are left out of both the statement and the params.
+//
+//	query, params, err := sqld.InsertFromModel(pizza, "id")
+//	// INSERT INTO pizzas (name, price) VALUES (:name, :price)
+//	query, args, err := sqlx.Named(query, params)
+func InsertFromModel[M Model](model M, omit ...string) (string, Params, error) {
+	values, err := StructValues(model)
+	if err != nil {
+		return "", nil, fmt.Errorf("insert from model: %w", err)
+	}
+
+	columns := make([]string, 0, len(values))
+	names := make([]string, 0, len(values))
+	params := make(Params, len(values))
+	for _, f := range modelFields[M]() {
+		if slices.Contains(omit, f.column) {
+			continue
+		}
+
+		columns = append(columns, f.column)
+		names = append(names, ":"+f.column)
+		params[f.column] = values[f.column]
+	}
+
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("insert from model: %w", ErrNoColumns)
+	}
+
+	return "INSERT INTO " + model.TableName() + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(names, ", ") + ")", params, nil
+}
+
+// FieldByColumn finds the struct field of a `Model` mapped to the provided column,
+// which can be qualified with `Model.TableName()`
+func FieldByColumn[M Model](column string) (reflect.StructField, bool) {
+	var model M
+	column = strings.TrimPrefix(column, model.TableName()+".")
+
+	for _, f := range modelFields[M]() {
+		if f.column == column {
+			return f.field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
 // TableName is a generic proxy for `Model.TableName()`
 func TableName[M Model]() string {
 	var model M
 	return model.TableName()
 }
 
+// ModelWildcard builds a callback that returns the wildcard qualified with `Model.TableName()`
+//
+//	sqld.Select(sqld.ModelWildcard[Pizza](), sqld.Columns("topping.name"))
+func ModelWildcard[M Model]() SqldFn {
+	return TableWildcard(TableName[M]())
+}
+
 // TableColumn returns a combination of `Model.TableName()` and the provided column.
 // Panics if the column is not present in the model
 func TableColumn[M Model](column string) string {
@@ -53,8 +299,74 @@ func TableColumn[M Model](column string) string {
 func TableColumnErr[M Model](column string) (string, error) {
 	var model M
 	if !slices.Contains(TableColumns[M](), model.TableName()+"."+column) {
-		return "", fmt.Errorf("column %s not present in model %T", column, *new(M))
+		return "", fmt.Errorf("column %s (model %T): %w", column, *new(M), ErrColumnNotInModel)
 	}
 
 	return TableName[M]() + "." + column, nil
 }
+
+// SoftDeleteColumn is the conventional column used by `WithSoftDelete()`
+const SoftDeleteColumn = "deleted_at"
+
+// NotDeleted builds a callback that excludes soft-deleted rows, checking that the column is NULL
+func NotDeleted(column string) SqldFn {
+	return Null(column)
+}
+
+// WithSoftDelete builds a callback that excludes the soft-deleted rows of a `Model`,
+// using its `SoftDeleteColumn`. Returns error if the column is not present in the model.
+//
+//	sqld.Where(sqld.And(
+//		sqld.WithSoftDelete[Pizza](),
+//		sqld.Eq("pizza.name", filters.Name),
+//	))
+func WithSoftDelete[M Model]() SqldFn {
+	return func() (string, []driver.Value, error) {
+		column, err := TableColumnErr[M](SoftDeleteColumn)
+		if err != nil {
+			return "", nil, fmt.Errorf("with soft delete: %w", err)
+		}
+
+		return NotDeleted(column)()
+	}
+}
+
+// WithTrashed is a no-op that replaces `WithSoftDelete()` to include the soft-deleted rows
+func WithTrashed() SqldFn {
+	return NoOp
+}
+
+// AntiJoin builds a callback that checks that the parent row has no matching child rows,
+// correlating `C.toCol` with `P.fromCol`. The child filter is ANDed to the correlation (use `NoOp` to skip it).
+//
+//	sqld.Where(
+//		sqld.AntiJoin[Author, Book]("id", "author_id",
+//			sqld.Eq("book.published", &published),
+//		),
+//	)
+func AntiJoin[P, C Model](fromCol, toCol string, childFilter SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		parentCol, err := TableColumnErr[P](fromCol)
+		if err != nil {
+			return "", nil, fmt.Errorf("anti join: %w", err)
+		}
+
+		childCol, err := TableColumnErr[C](toCol)
+		if err != nil {
+			return "", nil, fmt.Errorf("anti join: %w", err)
+		}
+
+		s, vals, err := NotExists(
+			New(
+				Select(Just("1")),
+				From(Just(TableName[C]())),
+				Where(And(ColumnEq(childCol, parentCol), childFilter)),
+			),
+		)()
+		if err != nil {
+			return "", nil, fmt.Errorf("anti join: %w", err)
+		}
+
+		return s, vals, nil
+	}
+}
@@ -1,6 +1,7 @@
 package sqld_legacy
 
 import (
+	"errors"
 	"slices"
 	"testing"
 )
@@ -20,3 +21,301 @@ func TestColumns(t *testing.T) {
 		t.Fatal("wrong columns extracted")
 	}
 }
+
+type testTimestamps struct {
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
+}
+
+type testAuditModel struct {
+	ID int `db:"id"`
+	testTimestamps
+	Owner testTimestamps `db:"owner"`
+}
+
+func (testAuditModel) TableName() string {
+	return "audit"
+}
+
+func TestColumnsEmbedded(t *testing.T) {
+	columns := TableColumns[testAuditModel]()
+	expected := []string{"audit.id", "audit.created_at", "audit.updated_at", "audit.owner"}
+	if !slices.Equal(columns, expected) {
+		t.Fatalf("embedded struct columns should be flattened: %v", columns)
+	}
+
+	field, ok := FieldByColumn[testAuditModel]("updated_at")
+	if !ok || field.Name != "UpdatedAt" || !slices.Equal(field.Index, []int{1, 1}) {
+		t.Fatalf("embedded field not resolved: %v", field)
+	}
+}
+
+type testSkipModel struct {
+	ID       int    `db:"id"`
+	Computed string `db:"-"`
+	internal string
+}
+
+func (testSkipModel) TableName() string {
+	return "skip"
+}
+
+func TestColumnsSkipped(t *testing.T) {
+	if columns := TableColumns[testSkipModel](); !slices.Equal(columns, []string{"skip.id"}) {
+		t.Fatalf("ignored and unexported fields should be skipped: %v", columns)
+	}
+	if _, ok := FieldByColumn[testSkipModel]("-"); ok {
+		t.Fatal("ignored field should not be resolved")
+	}
+}
+
+func TestStructValues(t *testing.T) {
+	model := testAuditModel{ID: 1, testTimestamps: testTimestamps{CreatedAt: "yesterday", UpdatedAt: "today"}}
+	params, err := StructValues(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 4 || params["id"] != 1 || params["created_at"] != "yesterday" || params["updated_at"] != "today" {
+		t.Fatalf("wrong values: %v", params)
+	}
+
+	params, err = StructValues(&testSkipModel{ID: 2, Computed: "skipped", internal: "skipped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 1 || params["id"] != 2 {
+		t.Fatalf("ignored and unexported fields should be skipped: %v", params)
+	}
+
+	params, err = StructValues(testModel{Hi: "hello", Named: "named"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["Hi"] != "hello" || params["nameddd"] != "named" {
+		t.Fatalf("untagged fields should fall back on the field name: %v", params)
+	}
+
+	if _, err := StructValues((*testModel)(nil)); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+	if _, err := StructValues(nil); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+	if _, err := StructValues(42); !errors.Is(err, ErrNotStruct) {
+		t.Fatalf("expected not struct error, got %v", err)
+	}
+}
+
+func TestInsertFromModel(t *testing.T) {
+	model := testAuditModel{ID: 1, testTimestamps: testTimestamps{CreatedAt: "yesterday", UpdatedAt: "today"}}
+	query, params, err := InsertFromModel(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "INSERT INTO audit (id, created_at, updated_at, owner) VALUES (:id, :created_at, :updated_at, :owner)" {
+		t.Fatalf("wrong INSERT: %s", query)
+	}
+	if len(params) != 4 || params["id"] != 1 || params["updated_at"] != "today" {
+		t.Fatalf("wrong params: %v", params)
+	}
+
+	query, params, err = InsertFromModel(model, "id", "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "INSERT INTO audit (created_at, updated_at) VALUES (:created_at, :updated_at)" {
+		t.Fatalf("omitted columns should be skipped: %s", query)
+	}
+	if _, ok := params["id"]; ok || len(params) != 2 {
+		t.Fatalf("omitted columns should not be in params: %v", params)
+	}
+
+	if _, _, err := InsertFromModel(testSkipModel{}, "id"); !errors.Is(err, ErrNoColumns) {
+		t.Fatalf("expected no columns error, got %v", err)
+	}
+}
+
+type testPointerModel struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+func (*testPointerModel) TableName() string {
+	return "pointers"
+}
+
+func TestInsertFromPointerModel(t *testing.T) {
+	query, params, err := InsertFromModel(&testPointerModel{ID: 1, Name: "margherita"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "INSERT INTO pointers (id, name) VALUES (:id, :name)" {
+		t.Fatalf("wrong INSERT: %s", query)
+	}
+	if len(params) != 2 || params["id"] != 1 || params["name"] != "margherita" {
+		t.Fatalf("wrong params: %v", params)
+	}
+
+	if columns := TableColumns[*testPointerModel](); !slices.Equal(columns, []string{"pointers.id", "pointers.name"}) {
+		t.Fatalf("pointer model columns should be extracted: %v", columns)
+	}
+
+	if _, _, err := InsertFromModel((*testPointerModel)(nil)); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
+
+type testTagModel struct {
+	ID      int    `col:"id" json:"uuid"`
+	Name    string `col:"full_name,pk" json:"name,omitempty"`
+	Comment string `json:"-"`
+}
+
+func (testTagModel) TableName() string {
+	return "tagged"
+}
+
+func TestColumnsTag(t *testing.T) {
+	if columns := TableColumnsTag[testTagModel]("col"); !slices.Equal(columns, []string{"tagged.id", "tagged.full_name", "tagged.Comment"}) {
+		t.Fatalf("wrong col columns: %v", columns)
+	}
+	if columns := TableColumnsTag[testTagModel]("json"); !slices.Equal(columns, []string{"tagged.uuid", "tagged.name"}) {
+		t.Fatalf("wrong json columns: %v", columns)
+	}
+
+	SetColumnTag("col")
+	defer SetColumnTag("db")
+
+	if columns := TableColumns[testTagModel](); !slices.Equal(columns, []string{"tagged.id", "tagged.full_name", "tagged.Comment"}) {
+		t.Fatalf("default tag should be used: %v", columns)
+	}
+	if params, err := StructValues(testTagModel{Name: "mario"}); err != nil || params["full_name"] != "mario" {
+		t.Fatalf("default tag should be used: %v %v", params, err)
+	}
+}
+
+func TestColumnsExceptOnly(t *testing.T) {
+	if columns := TableColumnsExcept[testAuditModel]("owner", "audit.created_at"); !slices.Equal(columns, []string{"audit.id", "audit.updated_at"}) {
+		t.Fatalf("wrong excluded columns: %v", columns)
+	}
+	if columns := TableColumnsOnly[testAuditModel]("updated_at", "id"); !slices.Equal(columns, []string{"audit.id", "audit.updated_at"}) {
+		t.Fatalf("selected columns should keep declaration order: %v", columns)
+	}
+
+	if _, err := TableColumnsOnlyErr[testAuditModel]("id", "missing"); !errors.Is(err, ErrColumnNotInModel) {
+		t.Fatalf("expected column not in model error, got %v", err)
+	}
+	if _, err := TableColumnsExceptErr[testAuditModel]("missing"); !errors.Is(err, ErrColumnNotInModel) {
+		t.Fatalf("expected column not in model error, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	TableColumnsOnly[testAuditModel]("missing")
+}
+
+func TestFieldByColumn(t *testing.T) {
+	field, ok := FieldByColumn[testModel]("nameddd")
+	if !ok || field.Name != "Named" {
+		t.Fatalf("tagged column not resolved: %v", field)
+	}
+
+	field, ok = FieldByColumn[testModel]("TestModel.Hi")
+	if !ok || field.Name != "Hi" {
+		t.Fatalf("untagged qualified column not resolved: %v", field)
+	}
+
+	if _, ok = FieldByColumn[testModel]("Named"); ok {
+		t.Fatal("tagged field should not be resolved by name")
+	}
+}
+
+type testChildModel struct {
+	ID       int `db:"id"`
+	ParentID int `db:"parent_id"`
+	Active   bool
+}
+
+func (testChildModel) TableName() string {
+	return "TestChild"
+}
+
+func TestAntiJoin(t *testing.T) {
+	active := true
+	s, vals, err := AntiJoin[testModel, testChildModel]("Hi", "parent_id", Eq("TestChild.Active", &active))()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "NOT EXISTS (\nSELECT\n\t1\nFROM TestChild\nWHERE\n\t(TestChild.parent_id = TestModel.Hi\nAND TestChild.Active = ?\n)\n\n\n)"
+	if s != expected {
+		t.Fatalf("wrong anti join:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 1 || vals[0] != &active {
+		t.Fatalf("child filter values should flow through: %v", vals)
+	}
+
+	if _, _, err = AntiJoin[testModel, testChildModel]("Hi", "missing", NoOp)(); err == nil {
+		t.Fatal("unknown column should fail")
+	}
+}
+
+func TestTableColumnErr(t *testing.T) {
+	if _, err := TableColumnErr[testModel]("missing"); !errors.Is(err, ErrColumnNotInModel) {
+		t.Fatalf("expected column not in model error, got %v", err)
+	}
+}
+
+type testSoftDeleteModel struct {
+	ID        int     `db:"id"`
+	DeletedAt *string `db:"deleted_at"`
+}
+
+func (testSoftDeleteModel) TableName() string {
+	return "pizza"
+}
+
+func TestWithSoftDelete(t *testing.T) {
+	name := "margherita"
+	for _, trashed := range []bool{false, true} {
+		softDelete := WithSoftDelete[testSoftDeleteModel]()
+		if trashed {
+			softDelete = WithTrashed()
+		}
+
+		s, vals, err := Where(And(softDelete, Eq("pizza.name", &name)))()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "WHERE\n\t(pizza.deleted_at IS NULL\nAND pizza.name = ?\n)\n"
+		if trashed {
+			expected = "WHERE\n\t(pizza.name = ?\n)\n"
+		}
+		if s != expected || len(vals) != 1 {
+			t.Fatalf("wrong soft delete filter (trashed: %v): %q %v", trashed, s, vals)
+		}
+	}
+
+	if s, _, _ := WithTrashed()(); s != "" {
+		t.Fatalf("opt-out should be empty: %q", s)
+	}
+	if _, _, err := WithSoftDelete[testModel]()(); !errors.Is(err, ErrColumnNotInModel) {
+		t.Fatalf("expected column not in model error, got %v", err)
+	}
+}
+
+func TestWildcards(t *testing.T) {
+	s, _, err := Select(TableWildcard("users"), Columns("orders.total"))()
+	if err != nil || s != "SELECT\n\tusers.*,\n\torders.total" {
+		t.Fatalf("wrong table wildcard: %q %v", s, err)
+	}
+
+	s, _, err = Select(ModelWildcard[testModel]())()
+	if err != nil || s != "SELECT\n\tTestModel.*" {
+		t.Fatalf("wrong model wildcard: %q %v", s, err)
+	}
+}
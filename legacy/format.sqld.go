@@ -0,0 +1,78 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// Format describes how the whitespace of a generated query is laid out
+type Format struct {
+	// Indent replaces every level of indentation (a tab, in the generated queries)
+	Indent string
+	// Compact renders the whole query on a single line, ignoring Indent
+	Compact bool
+}
+
+var (
+	// Pretty keeps the generated layout: a line for each statement, tab-indented
+	Pretty = Format{Indent: "\t"}
+	// Compact renders the query on a single line, handy for logs
+	Compact = Format{Compact: true}
+)
+
+// Indented returns a multiline Format that uses the provided string for indentation.
+//
+//	sqld.BuildWith(sqld.New(...), sqld.Indented("  "))
+func Indented(indent string) Format {
+	return Format{Indent: indent}
+}
+
+// Apply rewrites the layout of the query; whitespace inside string literals,
+// quoted identifiers and dollar-quoted blocks is left untouched
+func (f Format) Apply(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+
+	last := 0
+	for _, index := range codeIndexes(query, func(i int) bool {
+		return query[i] == '\n'
+	}) {
+		end := index + 1
+		for end < len(query) && query[end] == '\t' {
+			end++
+		}
+
+		code := query[last:index]
+		sb.WriteString(code)
+		last = end
+
+		if !f.Compact {
+			sb.WriteString("\n" + strings.Repeat(f.Indent, end-index-1))
+			continue
+		}
+
+		// no padding at the edges of the query and inside parentheses
+		out := sb.String()
+		if out == "" || end == len(query) || strings.HasSuffix(out, "(") || strings.HasSuffix(out, " ") ||
+			query[end] == ')' || query[end] == '\n' {
+			continue
+		}
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(query[last:])
+
+	return sb.String()
+}
+
+// BuildWith runs the operator and lays out the resulting query with the provided Format.
+// Values are returned untouched.
+//
+//	query, args, err := sqld.BuildWith(sqld.New(...), sqld.Compact)
+func BuildWith(op SqldFn, f Format) (string, []driver.Value, error) {
+	query, vals, err := op()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return f.Apply(query), vals, nil
+}
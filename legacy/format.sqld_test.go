@@ -0,0 +1,54 @@
+package sqld_legacy
+
+import "testing"
+
+func TestBuildWith(t *testing.T) {
+	name := "margherita\n\twith basil"
+	price := 5
+	query := New(
+		Select(Columns("id", "name")),
+		From(Just("pizzas")),
+		Where(And(
+			Eq("name", &name),
+			Eq("price", &price),
+		)),
+	)
+
+	pretty, prettyVals, err := BuildWith(query, Pretty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _, _ := query()
+	if pretty != raw {
+		t.Fatalf("pretty format should keep the generated layout: %q", pretty)
+	}
+
+	compact, compactVals, err := BuildWith(query, Compact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compact != "SELECT id, name FROM pizzas WHERE (name = ? AND price = ?)" {
+		t.Fatalf("wrong compact format: %q", compact)
+	}
+
+	indented, _, err := BuildWith(query, Indented("  "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indented != "SELECT\n  id,\n  name\nFROM pizzas\nWHERE\n  (name = ?\nAND price = ?\n)\n\n" {
+		t.Fatalf("wrong indented format: %q", indented)
+	}
+
+	for _, vals := range [][]any{{prettyVals[0], prettyVals[1]}, {compactVals[0], compactVals[1]}} {
+		if vals[0] != &name || vals[1] != &price {
+			t.Fatalf("values should be untouched: %v", vals)
+		}
+	}
+}
+
+func TestFormatLiterals(t *testing.T) {
+	query := "SELECT\n\t'a\n\tb' AS x\nFROM pizzas"
+	if s := Compact.Apply(query); s != "SELECT 'a\n\tb' AS x FROM pizzas" {
+		t.Fatalf("whitespace inside literals should be untouched: %q", s)
+	}
+}
@@ -0,0 +1,226 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Insert builds a callback that returns an INSERT statement on the provided table,
+// with a VALUES row for each of the provided rows.
+// If no rows are provided, the VALUES part is omitted (e.g. to insert from a SELECT).
+//
+//	sqld.New(
+//		sqld.Insert(sqld.Just("pizzas"), []string{"name", "price"},
+//			[]driver.Value{"margherita", 5},
+//			[]driver.Value{"diavola", 6},
+//		),
+//		sqld.Returning(sqld.Columns("id")),
+//	)
+func Insert(table SqldFn, columns []string, rows ...[]driver.Value) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(columns) == 0 {
+			return "", nil, fmt.Errorf("insert: %w", ErrNoColumns)
+		}
+
+		t, vals, err := table()
+		if err != nil {
+			return "", nil, fmt.Errorf("insert: %w", err)
+		}
+
+		var sb strings.Builder
+		sb.WriteString("INSERT INTO " + t + " (" + strings.Join(columns, ", ") + ")")
+
+		if len(rows) == 0 {
+			return sb.String(), vals, nil
+		}
+
+		sb.WriteString("\nVALUES\n\t")
+		for i, row := range rows {
+			if len(row) != len(columns) {
+				return "", nil, fmt.Errorf("insert (row %d): %w", i, ErrColumnCountMismatch)
+			}
+
+			if i != 0 {
+				sb.WriteString(",\n\t")
+			}
+			sb.WriteString("(" + placeholders(len(row)) + ")")
+
+			vals = append(vals, row...)
+		}
+
+		return sb.String(), vals, nil
+	}
+}
+
+// Values builds a callback that returns a VALUES row constructor, flattening the values of every row.
+// Combined with `SubQuery()` it can be used as an inline table:
+//
+//	sqld.Join(sqld.INNER_JOIN,
+//		sqld.SubQuery(sqld.Values(
+//			[]driver.Value{1, "margherita"},
+//			[]driver.Value{2, "diavola"},
+//		), "p(id, name)"),
+//		sqld.ColumnEq("p.id", "orders.pizza_id"),
+//	)
+func Values(rows ...[]driver.Value) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(rows) == 0 {
+			return "", nil, fmt.Errorf("values: %w", ErrEmptySlice)
+		}
+
+		var sb strings.Builder
+		sb.WriteString("VALUES ")
+
+		vals := make([]driver.Value, 0, len(rows)*len(rows[0]))
+		for i, row := range rows {
+			if len(row) == 0 {
+				return "", nil, fmt.Errorf("values (row %d): %w", i, ErrEmptySlice)
+			}
+			if len(row) != len(rows[0]) {
+				return "", nil, fmt.Errorf("values (row %d): %w", i, ErrColumnCountMismatch)
+			}
+
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(" + placeholders(len(row)) + ")")
+
+			vals = append(vals, row...)
+		}
+
+		return sb.String(), vals, nil
+	}
+}
+
+// Returning builds a callback that returns a RETURNING statement with a concatenation of
+// the provided operators.
+func Returning(ops ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := Select(ops...)()
+		if err != nil {
+			return "", nil, fmt.Errorf("returning: %w", err)
+		}
+
+		return "RETURNING" + strings.TrimPrefix(s, "SELECT"), vals, nil
+	}
+}
+
+// Set builds a callback that assigns the provided value to a column, to be used in `Update()`.
+// If the value is nil, the assignment is skipped.
+//
+//	sqld.Set("name", patch.Name)
+func Set[T driver.Value](columnExpr string, val *T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if val == nil {
+			return "", nil, nil
+		}
+
+		return columnExpr + " = ?", []driver.Value{val}, nil
+	}
+}
+
+// Update builds a callback that returns an UPDATE statement on the provided table,
+// with a SET list made of the non-empty assignments.
+// Returns `ErrNoOps` if all the assignments are empty.
+//
+//	sqld.New(
+//		sqld.Update(sqld.Just("pizzas"),
+//			sqld.Set("name", patch.Name),
+//			sqld.Set("price", patch.Price),
+//		),
+//		sqld.Where(sqld.Eq("id", &id)),
+//	)
+func Update(table SqldFn, sets ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		t, vals, err := table()
+		if err != nil {
+			return "", nil, fmt.Errorf("update: %w", err)
+		}
+
+		s, setVals, err := setList(sets...)
+		if err != nil {
+			return "", nil, fmt.Errorf("update: %w", err)
+		}
+
+		return "UPDATE " + t + "\nSET " + s, append(vals, setVals...), nil
+	}
+}
+
+// setList joins the non-empty assignments, returning `ErrNoOps` if they are all empty
+func setList(sets ...SqldFn) (string, []driver.Value, error) {
+	assignments, vals := make([]string, 0, len(sets)), make([]driver.Value, 0, len(sets))
+	for _, set := range sets {
+		s, setVals, err := set()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if s == "" {
+			continue
+		}
+
+		assignments = append(assignments, s)
+
+		if len(setVals) != 0 {
+			vals = append(vals, setVals...)
+		}
+	}
+
+	if len(assignments) == 0 {
+		return "", nil, ErrNoOps
+	}
+
+	return strings.Join(assignments, ", "), vals, nil
+}
+
+// Delete builds a callback that returns a DELETE statement on the provided table.
+//
+//	sqld.New(
+//		sqld.Delete(sqld.Just("pizzas")),
+//		sqld.Where(sqld.Eq("id", &id)),
+//		sqld.Returning(sqld.Columns("id")),
+//	)
+func Delete(table SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		t, vals, err := table()
+		if err != nil {
+			return "", nil, fmt.Errorf("delete: %w", err)
+		}
+
+		return "DELETE FROM " + t, vals, nil
+	}
+}
+
+// DeleteGuarded builds a callback that returns a DELETE statement on the provided table,
+// filtered by the provided WHERE operator.
+// Returns `ErrUnboundedDelete` if the WHERE operator is empty, instead of deleting every row.
+//
+//	sqld.New(
+//		sqld.DeleteGuarded(sqld.Just("pizzas"),
+//			sqld.Where(
+//				sqld.IfNotNil(filters.Name,
+//					sqld.Eq("name", filters.Name),
+//				),
+//			),
+//		),
+//	)
+func DeleteGuarded(table SqldFn, where SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := Delete(table)()
+		if err != nil {
+			return "", nil, err
+		}
+
+		w, whereVals, err := where()
+		if err != nil {
+			return "", nil, fmt.Errorf("delete: %w", err)
+		}
+
+		if w == "" {
+			return "", nil, fmt.Errorf("delete: %w", ErrUnboundedDelete)
+		}
+
+		return s + "\n" + w, append(vals, whereVals...), nil
+	}
+}
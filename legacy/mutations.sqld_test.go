@@ -0,0 +1,143 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	s, vals, err := New(
+		Insert(Just("pizzas"), []string{"name", "price"},
+			[]driver.Value{"margherita", 5},
+		),
+		Returning(Columns("id")),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "INSERT INTO pizzas (name, price)\nVALUES\n\t(?, ?)\nRETURNING\n\tid\n" {
+		t.Fatalf("wrong single-row INSERT: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != "margherita" || vals[1] != 5 {
+		t.Fatalf("wrong values: %v", vals)
+	}
+
+	s, vals, err = Insert(Just("pizzas"), []string{"name", "price"},
+		[]driver.Value{"margherita", 5},
+		[]driver.Value{"diavola", 6},
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "INSERT INTO pizzas (name, price)\nVALUES\n\t(?, ?),\n\t(?, ?)" {
+		t.Fatalf("wrong multi-row INSERT: %q", s)
+	}
+	if len(vals) != 4 || vals[0] != "margherita" || vals[1] != 5 || vals[2] != "diavola" || vals[3] != 6 {
+		t.Fatalf("values should be flattened in row-major order: %v", vals)
+	}
+
+	_, _, err = Insert(Just("pizzas"), []string{"name", "price"},
+		[]driver.Value{"margherita", 5},
+		[]driver.Value{"diavola"},
+	)()
+	if !errors.Is(err, ErrColumnCountMismatch) {
+		t.Fatalf("expected column count mismatch, got %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	id, price := 1, 7
+	var name *string
+
+	s, vals, err := New(
+		Update(Just("pizzas"),
+			Set("name", name),
+			Set("price", &price),
+			Set("id", &id),
+		),
+		Where(Eq("id", &id)),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "UPDATE pizzas\nSET price = ?, id = ?\nWHERE\n\tid = ?\n\n" {
+		t.Fatalf("wrong UPDATE: %q", s)
+	}
+	if len(vals) != 3 || vals[0] != &price || vals[1] != &id || vals[2] != &id {
+		t.Fatalf("wrong values ordering: %v", vals)
+	}
+
+	_, _, err = Update(Just("pizzas"), Set("name", name))()
+	if !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no ops error, got %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	id := 1
+	s, vals, err := New(
+		Delete(Just("pizzas")),
+		Where(Eq("id", &id)),
+		Returning(Columns("id")),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "DELETE FROM pizzas\nWHERE\n\tid = ?\n\nRETURNING\n\tid\n" || len(vals) != 1 || vals[0] != &id {
+		t.Fatalf("wrong DELETE: %q %v", s, vals)
+	}
+
+	var name *string
+	s, _, err = New(
+		Delete(Just("pizzas")),
+		Where(IfNotNil(name, Eq("name", name))),
+	)()
+	if err != nil || s != "DELETE FROM pizzas\n\n" {
+		t.Fatalf("unguarded DELETE should allow empty filters: %q %v", s, err)
+	}
+
+	_, _, err = DeleteGuarded(Just("pizzas"), Where(IfNotNil(name, Eq("name", name))))()
+	if !errors.Is(err, ErrUnboundedDelete) {
+		t.Fatalf("expected unbounded delete error, got %v", err)
+	}
+
+	s, vals, err = DeleteGuarded(Just("pizzas"), Where(Eq("id", &id)))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "DELETE FROM pizzas\nWHERE\n\tid = ?\n" || len(vals) != 1 || vals[0] != &id {
+		t.Fatalf("wrong guarded DELETE: %q %v", s, vals)
+	}
+}
+
+func TestValues(t *testing.T) {
+	s, vals, err := Values([]driver.Value{1, "margherita"})()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "VALUES (?, ?)" || len(vals) != 2 {
+		t.Fatalf("wrong single-row VALUES: %q %v", s, vals)
+	}
+
+	s, vals, err = SubQuery(Values(
+		[]driver.Value{1, "margherita"},
+		[]driver.Value{2, "diavola"},
+	), "p(id, name)")()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "(\nVALUES (?, ?), (?, ?)\n) AS p(id, name)" {
+		t.Fatalf("wrong inline table: %q", s)
+	}
+	if len(vals) != 4 || vals[0] != 1 || vals[1] != "margherita" || vals[2] != 2 || vals[3] != "diavola" {
+		t.Fatalf("values should be flattened in row-major order: %v", vals)
+	}
+
+	if _, _, err := Values()(); !errors.Is(err, ErrEmptySlice) {
+		t.Fatalf("expected empty slice error, got %v", err)
+	}
+	if _, _, err := Values([]driver.Value{1, "margherita"}, []driver.Value{2})(); !errors.Is(err, ErrColumnCountMismatch) {
+		t.Fatalf("expected column count mismatch, got %v", err)
+	}
+}
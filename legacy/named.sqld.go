@@ -0,0 +1,136 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Params is a map containing named query parameters, compatible with `sqlx.Named()`
+// and the `Params` of the main sqld package
+type Params map[string]any
+
+// BuildNamed runs the operator and swaps all ? placeholders with named ones (:arg0, :arg1...),
+// returning the values mapped by name, ready for `sqlx.Named()`.
+// Every reference to the same SharedArg gets the same name.
+//
+//	query, params, err := sqld.BuildNamed(sqld.New(...))
+//	query, args, err := sqlx.Named(query, params)
+func BuildNamed(op SqldFn) (string, Params, error) {
+	query, vals, err := op()
+	if err != nil {
+		return "", nil, err
+	}
+
+	params := make(Params, len(vals))
+	shared := make(map[*SharedArg]string)
+
+	query = replacePlaceholders(query, len(vals), func(i int) string {
+		sharedArg, ok := vals[i].(*SharedArg)
+		if !ok {
+			name := "arg" + strconv.Itoa(len(params))
+			params[name] = vals[i]
+			return ":" + name
+		}
+
+		name, ok := shared[sharedArg]
+		if !ok {
+			name = "arg" + strconv.Itoa(len(params))
+			params[name] = sharedArg.val
+			shared[sharedArg] = name
+		}
+
+		return ":" + name
+	})
+
+	return query, params, nil
+}
+
+// isNameChar reports whether the byte can be part of a named parameter
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+// FromNamed builds a callback that embeds a fragment with named parameters (:name),
+// like the ones built with the main sqld package, swapping them with ? placeholders
+// and binding the corresponding values.
+// Postgres casts (::type) and names inside literals are left untouched.
+// Slice parameters (e.g. from the `In()` of the main package) are expanded to a placeholder for each element,
+// like `sqlx.In()` does.
+// Returns `ErrMissingParam` if a parameter has no value, and `ErrEmptySlice` if a slice parameter is empty.
+//
+//	params := make(sqld.Params)
+//	filter := sqld.IfNotNil(filters.Name, &params, sqld.Eq("name"))
+//
+//	sqld_legacy.Where(sqld_legacy.FromNamed(filter, sqld_legacy.Params(params)))
+func FromNamed(fragment string, params Params) SqldFn {
+	return func() (string, []driver.Value, error) {
+		indexes := codeIndexes(fragment, func(i int) bool {
+			return fragment[i] == ':' && i+1 < len(fragment) && isNameChar(fragment[i+1], true) &&
+				(i == 0 || fragment[i-1] != ':')
+		})
+
+		var sb strings.Builder
+		vals := make([]driver.Value, 0, len(indexes))
+
+		last := 0
+		for _, index := range indexes {
+			end := index + 1
+			for end < len(fragment) && isNameChar(fragment[end], false) {
+				end++
+			}
+
+			name := fragment[index+1 : end]
+			val, ok := params[name]
+			if !ok {
+				return "", nil, fmt.Errorf("from named (%s): %w", name, ErrMissingParam)
+			}
+
+			sb.WriteString(fragment[last:index])
+			last = end
+
+			elems, ok := expandSlice(val)
+			if !ok {
+				sb.WriteRune('?')
+				vals = append(vals, val)
+				continue
+			}
+
+			if len(elems) == 0 {
+				return "", nil, fmt.Errorf("from named (%s): %w", name, ErrEmptySlice)
+			}
+
+			sb.WriteString(placeholders(len(elems)))
+			vals = append(vals, elems...)
+		}
+		sb.WriteString(fragment[last:])
+
+		return sb.String(), vals, nil
+	}
+}
+
+// expandSlice returns the elements of a slice or array parameter (or a pointer to one).
+// Byte slices and `driver.Valuer` values (e.g. `pq.Array()`) are bound as they are
+func expandSlice(val any) ([]driver.Value, bool) {
+	if _, ok := val.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems := make([]driver.Value, v.Len())
+	for i := range elems {
+		elems[i] = v.Index(i).Interface()
+	}
+
+	return elems, true
+}
@@ -0,0 +1,109 @@
+package sqld_legacy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuildNamed(t *testing.T) {
+	name := "test"
+	pizzas := []string{"margherita", "diavola"}
+
+	s, params, err := BuildNamed(
+		Where(
+			And(
+				Eq("name", &name),
+				In("pizzas", &pizzas),
+				Just("note != 'why?'"),
+			),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "WHERE\n\t(name = :arg0\nAND pizzas IN (:arg1, :arg2)\nAND note != 'why?'\n)\n" {
+		t.Fatalf("wrong named placeholders: %q", s)
+	}
+	if len(params) != 3 || params["arg0"] != &name || params["arg1"] != "margherita" || params["arg2"] != "diavola" {
+		t.Fatalf("wrong params: %v", params)
+	}
+
+	since := Arg(42)
+	s, params, err = BuildNamed(And(Eq("a", since), Eq("b", since)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "(a = :arg0\nAND b = :arg0\n)" || len(params) != 1 || params["arg0"] != 42 {
+		t.Fatalf("shared arg should get a single name: %q %v", s, params)
+	}
+}
+
+func TestFromNamed(t *testing.T) {
+	// as built by the main sqld package:
+	//	sqld.Or(
+	//		sqld.IfNotZero(name, &params, sqld.Eq("name")),
+	//		sqld.IfNotZero(since, &params, sqld.Eq("created_at")),
+	//	)
+	fragment := "(\n\tname = :arg0 OR\n\tcreated_at = :arg1::timestamp\n)"
+	params := Params{"arg0": "test", "arg1": "2024-01-01"}
+
+	pizzas := []string{"margherita"}
+	s, vals, err := New(
+		Select(AllWildcard()),
+		From(Just("pizzas")),
+		Where(
+			And(
+				In("pizzas", &pizzas),
+				FromNamed(fragment, params),
+				Just("note != ':arg2'"),
+			),
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT\n\t*\nFROM pizzas\nWHERE\n\t(pizzas IN (?)\nAND (\n\tname = ? OR\n\tcreated_at = ?::timestamp\n)\nAND note != ':arg2'\n)\n\n"
+	if s != expected {
+		t.Fatalf("wrong bridged query:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 3 || vals[0] != "margherita" || vals[1] != "test" || vals[2] != "2024-01-01" {
+		t.Fatalf("wrong bridged values: %v", vals)
+	}
+
+	if _, _, err = FromNamed("name = :missing", params)(); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("expected missing param error, got %v", err)
+	}
+}
+
+func TestFromNamedSlice(t *testing.T) {
+	// as built by the main sqld package:
+	//	sqld.And(
+	//		sqld.IfNotEmpty(ids, &params, sqld.In("id")),
+	//		sqld.IfNotEmpty(names, &params, sqld.NotIn("name")),
+	//	)
+	fragment := "(\n\tid IN(:arg0) AND\n\tname NOT IN(:arg1)\n)"
+	names := []string{"diavola"}
+	params := Params{"arg0": []int{1, 2}, "arg1": &names}
+
+	s, vals, err := FromNamed(fragment, params)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "(\n\tid IN(?, ?) AND\n\tname NOT IN(?)\n)" {
+		t.Fatalf("slice params should be expanded: %q", s)
+	}
+	if len(vals) != 3 || vals[0] != 1 || vals[1] != 2 || vals[2] != "diavola" {
+		t.Fatalf("slice elements should be bound one by one: %v", vals)
+	}
+
+	s, vals, err = FromNamed("data = :arg0", Params{"arg0": []byte("raw")})()
+	if err != nil || s != "data = ?" || len(vals) != 1 {
+		t.Fatalf("byte slices should be bound as they are: %q %v %v", s, vals, err)
+	}
+
+	if _, _, err = FromNamed("id IN(:arg0)", Params{"arg0": []int{}})(); !errors.Is(err, ErrEmptySlice) {
+		t.Fatalf("expected empty slice error, got %v", err)
+	}
+}
@@ -2,9 +2,11 @@ package sqld_legacy
 
 import (
 	"database/sql/driver"
-	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // Just returns a callback that just returns the provided string
@@ -14,6 +16,49 @@ func Just(s string) SqldFn {
 	}
 }
 
+// Raw is like `Just()`, binding the provided values to the placeholders of the fragment.
+// Returns `ErrPlaceholderMismatch` if the number of ? placeholders (outside literals) differs from the values count.
+//
+//	sqld.Raw("created_at > now() - make_interval(days => ?)", days)
+func Raw(sql string, vals ...driver.Value) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if count := CountPlaceholders(sql); count != len(vals) {
+			return "", nil, fmt.Errorf("raw (%d placeholders, %d values): %w", count, len(vals), ErrPlaceholderMismatch)
+		}
+
+		return sql, vals, nil
+	}
+}
+
+// Val builds a callback that binds the provided value to a standalone placeholder
+//
+//	sqld.Greatest(sqld.Just("balance"), sqld.Val(0)) // GREATEST(balance, ?)
+func Val(v driver.Value) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return "?", []driver.Value{v}, nil
+	}
+}
+
+// ValPtr is like `Val()`, but returns an empty string if the value is nil
+func ValPtr[T driver.Value](v *T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if v == nil {
+			return "", nil, nil
+		}
+
+		return "?", []driver.Value{*v}, nil
+	}
+}
+
+// Lit builds a callback that returns the provided literal as is, like `Just()`.
+//
+// The literal is not escaped nor bound: never use it with user input, use `Val()` instead.
+//
+//	sqld.CoalesceOps(sqld.Just("nickname"), sqld.Lit("'anonymous'"))
+func Lit(s string) SqldFn {
+	return Just(s)
+}
+
 // Columns builds a callback that returns a list of columns, comma-separated
 func Columns(columns ...string) SqldFn {
 	return func() (string, []driver.Value, error) {
@@ -33,54 +78,271 @@ func Select(ops ...SqldFn) SqldFn {
 			return "", nil, fmt.Errorf("select: %w", ErrNoOps)
 		}
 
-		columns, vals := make([]string, 0, len(ops)), make([]driver.Value, 0)
+		columns, size, vals, errs := runOps(ops)
+		if errs != nil {
+			return "", nil, fmt.Errorf("select: %w", errs)
+		}
+
+		if len(columns) == 0 {
+			return "", nil, fmt.Errorf("select: %w", ErrNoColumns)
+		}
+
+		return joinParts("SELECT\n\t", columns, size, ",\n\t", ""), vals, nil
+	}
+}
+
+func aggregate(name string, op SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(name), err)
+		}
+
+		return name + "(" + s + ")", vals, nil
+	}
+}
+
+// Count builds a callback that returns a COUNT function with the given argument
+func Count(op SqldFn) SqldFn {
+	return aggregate("COUNT", op)
+}
+
+// Sum builds a callback that returns a SUM function with the given argument
+func Sum(op SqldFn) SqldFn {
+	return aggregate("SUM", op)
+}
+
+// DistinctExpr builds a callback that prefixes the expression with DISTINCT, to be used in aggregates.
+//
+//	sqld.Count(sqld.DistinctExpr(sqld.Just("user_id"))) // COUNT(DISTINCT user_id)
+func DistinctExpr(op SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("distinct: %w", err)
+		}
+
+		return "DISTINCT " + s, vals, nil
+	}
+}
+
+// CountDistinct builds a callback that returns a COUNT function of the distinct values of the given argument
+func CountDistinct(op SqldFn) SqldFn {
+	return Count(DistinctExpr(op))
+}
+
+// SumDistinct builds a callback that returns a SUM function of the distinct values of the given argument
+func SumDistinct(op SqldFn) SqldFn {
+	return Sum(DistinctExpr(op))
+}
+
+// Avg builds a callback that returns an AVG function with the given argument
+func Avg(op SqldFn) SqldFn {
+	return aggregate("AVG", op)
+}
+
+// Min builds a callback that returns a MIN function with the given argument
+func Min(op SqldFn) SqldFn {
+	return aggregate("MIN", op)
+}
+
+// Max builds a callback that returns a MAX function with the given argument
+func Max(op SqldFn) SqldFn {
+	return aggregate("MAX", op)
+}
+
+// Coalesce builds a callback that returns an coalesced expression
+func Coalesce(op SqldFn, fallback string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("coalesce: %w", err)
+		}
+
+		return fmt.Sprintf("COALESCE(%s, %s)", s, fallback), vals, nil
+	}
+}
+
+// When builds a callback that returns a WHEN branch of a CASE expression.
+// If the condition is empty, the branch is skipped.
+func When(cond SqldFn, result SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		c, condVals, err := cond()
+		if err != nil {
+			return "", nil, fmt.Errorf("when: %w", err)
+		}
+
+		if c == "" {
+			return "", nil, nil
+		}
+
+		r, resultVals, err := result()
+		if err != nil {
+			return "", nil, fmt.Errorf("when: %w", err)
+		}
+
+		vals := make([]driver.Value, 0, len(condVals)+len(resultVals))
+		vals = append(vals, condVals...)
+		vals = append(vals, resultVals...)
+
+		return "WHEN " + c + " THEN " + r, vals, nil
+	}
+}
+
+// Else builds a callback that returns the ELSE branch of a CASE expression
+func Else(result SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		r, vals, err := result()
+		if err != nil {
+			return "", nil, fmt.Errorf("else: %w", err)
+		}
+
+		if r == "" {
+			return "", nil, nil
+		}
+
+		return "ELSE " + r, vals, nil
+	}
+}
+
+// Case builds a callback that returns a CASE expression combining the provided
+// `When()` and `Else()` branches, in order.
+//
+//	sqld.As(
+//		sqld.Case(
+//			sqld.When(sqld.Just("balance > 0"), sqld.Just("'pos'")),
+//			sqld.When(sqld.Just("balance < 0"), sqld.Just("'neg'")),
+//			sqld.Else(sqld.Just("'zero'")),
+//		),
+//		"sign",
+//	)
+func Case(branches ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(branches) == 0 {
+			return "", nil, fmt.Errorf("case: %w", ErrNoOps)
+		}
+
+		var sb strings.Builder
+		vals := make([]driver.Value, 0)
+
+		for _, branch := range branches {
+			s, branchVals, err := branch()
+			if err != nil {
+				return "", nil, fmt.Errorf("case: %w", err)
+			}
+
+			if s == "" {
+				continue
+			}
+
+			sb.WriteString(" " + s)
+
+			if len(branchVals) != 0 {
+				vals = append(vals, branchVals...)
+			}
+		}
+
+		if sb.Len() == 0 {
+			return "", nil, nil
+		}
+
+		return "CASE" + sb.String() + " END", vals, nil
+	}
+}
+
+// CoalesceOps builds a callback that returns the coalesced expression of all the operators,
+// with their values in order. Empty operators are skipped.
+//
+//	sqld.CoalesceOps(sqld.Just("nickname"), sqld.Just("name"), sqld.Just("'anonymous'"))
+func CoalesceOps(ops ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(ops) == 0 {
+			return "", nil, fmt.Errorf("coalesce: %w", ErrNoOps)
+		}
+
+		args, vals := make([]string, 0, len(ops)), make([]driver.Value, 0)
 		for _, op := range ops {
-			s, subVals, err := op()
+			s, opVals, err := op()
 			if err != nil {
-				return "", nil, fmt.Errorf("select: %w", err)
+				return "", nil, fmt.Errorf("coalesce: %w", err)
 			}
 
 			if s == "" {
 				continue
 			}
 
-			columns = append(columns, s)
+			args = append(args, s)
 
-			if len(subVals) != 0 {
-				vals = append(vals, subVals...)
+			if len(opVals) != 0 {
+				vals = append(vals, opVals...)
 			}
 		}
 
-		columnsJoin := strings.Join(columns, ",\n\t")
-		if columnsJoin == "" {
-			return "", nil, fmt.Errorf("select: %w", ErrNoColumns)
+		if len(args) == 0 {
+			return "", nil, nil
 		}
 
-		return "SELECT\n\t" + columnsJoin, vals, nil
+		return "COALESCE(" + strings.Join(args, ", ") + ")", vals, nil
 	}
 }
 
-// Count builds a callback that returns a COUNT function with the given argument
-func Count(op SqldFn) SqldFn {
+func extremum(name string, ops ...SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
-		s, vals, err := op()
-		if err != nil {
-			return "", nil, fmt.Errorf("count: %w", err)
+		if len(ops) == 0 {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(name), ErrNoOps)
 		}
 
-		return "COUNT(" + s + ")", vals, nil
+		args, vals := make([]string, 0, len(ops)), make([]driver.Value, 0)
+		for _, op := range ops {
+			s, opVals, err := op()
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", strings.ToLower(name), err)
+			}
+
+			if s == "" {
+				continue
+			}
+
+			args = append(args, s)
+
+			if len(opVals) != 0 {
+				vals = append(vals, opVals...)
+			}
+		}
+
+		if len(args) == 0 {
+			return "", nil, nil
+		}
+
+		return name + "(" + strings.Join(args, ", ") + ")", vals, nil
 	}
 }
 
-// Coalesce builds a callback that returns an coalesced expression
-func Coalesce(op SqldFn, fallback string) SqldFn {
+// Greatest builds a callback that returns the largest of the expressions,
+// with their values in order. Empty operators are skipped.
+//
+//	sqld.Greatest(sqld.Just("balance"), sqld.Val(0))
+func Greatest(ops ...SqldFn) SqldFn {
+	return extremum("GREATEST", ops...)
+}
+
+// Least builds a callback that returns the smallest of the expressions,
+// with their values in order. Empty operators are skipped.
+func Least(ops ...SqldFn) SqldFn {
+	return extremum("LEAST", ops...)
+}
+
+// Cast builds a callback that converts the expression to the provided SQL type
+//
+//	sqld.Cast(sqld.Just("created_at"), "date") // CAST(created_at AS date)
+func Cast(op SqldFn, sqlType string) SqldFn {
 	return func() (string, []driver.Value, error) {
 		s, vals, err := op()
 		if err != nil {
-			return "", nil, fmt.Errorf("coalesce: %w", err)
+			return "", nil, fmt.Errorf("cast: %w", err)
 		}
 
-		return fmt.Sprintf("COALESCE(%s, %s)", s, fallback), vals, nil
+		return "CAST(" + s + " AS " + sqlType + ")", vals, nil
 	}
 }
 
@@ -91,6 +353,13 @@ func AllWildcard() SqldFn {
 	}
 }
 
+// TableWildcard builds a callback that returns the wildcard qualified with the table name (table.*)
+func TableWildcard(table string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return table + ".*", nil, nil
+	}
+}
+
 // From builds a callback that just returns a FROM statement with the provided table
 func From(op SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
@@ -103,6 +372,80 @@ func From(op SqldFn) SqldFn {
 	}
 }
 
+func combine(keyword string, queries ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(queries) == 0 {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(keyword), ErrNoOps)
+		}
+
+		var sb strings.Builder
+		vals := make([]driver.Value, 0)
+
+		for _, query := range queries {
+			s, queryVals, err := query()
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", strings.ToLower(keyword), err)
+			}
+
+			if s == "" {
+				continue
+			}
+
+			if sb.Len() != 0 {
+				sb.WriteString("\n" + keyword + "\n")
+			}
+			sb.WriteString(s)
+
+			if len(queryVals) != 0 {
+				vals = append(vals, queryVals...)
+			}
+		}
+
+		return sb.String(), vals, nil
+	}
+}
+
+// Union builds a callback combining the results of the provided queries, removing duplicates.
+// Empty queries are skipped.
+//
+//	sqld.Union(
+//		sqld.New(sqld.Select(...), sqld.From(sqld.Just("customers"))),
+//		sqld.New(sqld.Select(...), sqld.From(sqld.Just("suppliers"))),
+//	)
+func Union(queries ...SqldFn) SqldFn {
+	return combine("UNION", queries...)
+}
+
+// UnionAll builds a callback combining the results of the provided queries, keeping duplicates.
+// Empty queries are skipped.
+func UnionAll(queries ...SqldFn) SqldFn {
+	return combine("UNION ALL", queries...)
+}
+
+// Intersect builds a callback returning the rows present in all the provided queries, removing duplicates.
+// Empty queries are skipped.
+func Intersect(queries ...SqldFn) SqldFn {
+	return combine("INTERSECT", queries...)
+}
+
+// IntersectAll builds a callback returning the rows present in all the provided queries, keeping duplicates.
+// Empty queries are skipped.
+func IntersectAll(queries ...SqldFn) SqldFn {
+	return combine("INTERSECT ALL", queries...)
+}
+
+// Except builds a callback returning the rows of the first query not present in the following ones,
+// removing duplicates. Empty queries are skipped.
+func Except(queries ...SqldFn) SqldFn {
+	return combine("EXCEPT", queries...)
+}
+
+// ExceptAll builds a callback returning the rows of the first query not present in the following ones,
+// keeping duplicates. Empty queries are skipped.
+func ExceptAll(queries ...SqldFn) SqldFn {
+	return combine("EXCEPT ALL", queries...)
+}
+
 type JoinType string
 
 const (
@@ -119,7 +462,15 @@ const (
 )
 
 // Join builds a callback that returns a JOIN statement of the provided type
-// with the desired subject, with a condition callback
+// with the desired subject, with a condition callback.
+// The condition can be any operator, including `And()`/`Or()` trees:
+//
+//	sqld.Join(sqld.INNER_JOIN, sqld.Just("orders"),
+//		sqld.And(
+//			sqld.ColumnEq("orders.user_id", "users.id"),
+//			sqld.Eq("orders.status", &status),
+//		),
+//	)
 func Join(joinType JoinType, subject SqldFn, op SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
 		subj, subjVals, err := subject()
@@ -134,19 +485,88 @@ func Join(joinType JoinType, subject SqldFn, op SqldFn) SqldFn {
 
 		vals := make([]driver.Value, 0, len(subjVals)+len(condVals))
 		if len(subjVals) != 0 {
-			vals = append(vals, subjVals)
+			vals = append(vals, subjVals...)
 		}
 		if len(condVals) != 0 {
-			vals = append(vals, condVals)
+			vals = append(vals, condVals...)
 		}
 
 		return string(joinType) + " JOIN " + subj + " ON " + cond, vals, nil
 	}
 }
 
-// As builds a callback that returns an alias
+// JoinOn is a shortcut for `Join()` combining all the conditions with AND
+func JoinOn(joinType JoinType, subject SqldFn, conds ...SqldFn) SqldFn {
+	return Join(joinType, subject, And(conds...))
+}
+
+// lateral prefixes the subquery with the LATERAL keyword
+func lateral(sub SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := sub()
+		if err != nil {
+			return "", nil, fmt.Errorf("lateral: %w", err)
+		}
+
+		return "LATERAL " + s, vals, nil
+	}
+}
+
+// LateralJoin is like `Join()`, with a LATERAL subquery that can reference the preceding tables.
+// The subquery values come before the condition ones.
+//
+//	sqld.LateralJoin(sqld.LEFT_JOIN,
+//		sqld.SubQuery(sqld.New(
+//			sqld.Select(sqld.Columns("orders.total")),
+//			sqld.From(sqld.Just("orders")),
+//			sqld.Where(sqld.ColumnEq("orders.user_id", "users.id")),
+//			sqld.OrderBy(sqld.Desc("orders.created_at")),
+//			sqld.Limit(&one),
+//		), "last_order"),
+//		sqld.Just("TRUE"),
+//	)
+func LateralJoin(joinType JoinType, sub SqldFn, op SqldFn) SqldFn {
+	return Join(joinType, lateral(sub), op)
+}
+
+// CrossJoinLateral builds a callback that returns a CROSS JOIN LATERAL statement with the subquery,
+// which can reference the preceding tables
+func CrossJoinLateral(sub SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := lateral(sub)()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s join: %w", CROSS_JOIN, err)
+		}
+
+		return string(CROSS_JOIN) + " JOIN " + s, vals, nil
+	}
+}
+
+// JoinUsing builds a callback that returns a JOIN statement of the provided type
+// with the desired subject, joining on the columns shared by both tables
+func JoinUsing(joinType JoinType, subject SqldFn, columns ...string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(columns) == 0 {
+			return "", nil, fmt.Errorf("%s join: %w", joinType, ErrNoColumns)
+		}
+
+		subj, vals, err := subject()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s join: %w", joinType, err)
+		}
+
+		return string(joinType) + " JOIN " + subj + " USING (" + strings.Join(columns, ", ") + ")", vals, nil
+	}
+}
+
+// As builds a callback that returns an alias.
+// Returns `ErrInvalidAlias` if the alias is not a plain identifier: use `QuotedAs()` for the other ones.
 func As(op SqldFn, aliasName string) SqldFn {
 	return func() (string, []driver.Value, error) {
+		if !isAlias(aliasName) {
+			return "", nil, fmt.Errorf("as (%q): %w", aliasName, ErrInvalidAlias)
+		}
+
 		s, vals, err := op()
 		if err != nil {
 			return "", nil, fmt.Errorf("as: %w", err)
@@ -156,9 +576,30 @@ func As(op SqldFn, aliasName string) SqldFn {
 	}
 }
 
-// SubQuery builds a callback that returns a subquery
+// QuotedAs is like `As()`, quoting the alias for the current dialect (see `Quote()`)
+// so that it can contain any character.
+//
+//	sqld.QuotedAs(sqld.Count(sqld.Just("id")), "Total orders") // COUNT(id) AS "Total orders"
+func QuotedAs(op SqldFn, aliasName string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("as: %w", err)
+		}
+
+		return s + " AS " + Quote(aliasName), vals, nil
+	}
+}
+
+// SubQuery builds a callback that returns a subquery.
+// The alias can declare the column aliases, like `t(id, name)`.
+// Returns `ErrInvalidAlias` if the alias is not a plain identifier.
 func SubQuery(op SqldFn, aliasName string) SqldFn {
 	return func() (string, []driver.Value, error) {
+		if !isAlias(aliasName) {
+			return "", nil, fmt.Errorf("subquery (%q): %w", aliasName, ErrInvalidAlias)
+		}
+
 		s, vals, err := op()
 		if err != nil {
 			return "", nil, fmt.Errorf("as: %w", err)
@@ -168,6 +609,49 @@ func SubQuery(op SqldFn, aliasName string) SqldFn {
 	}
 }
 
+// ScalarSubQuery builds a callback that returns a parenthesized subquery, to be used as an expression
+// (e.g. in `Select()` or `Coalesce()`). Returns `ErrNoOps` if the subquery is empty.
+func ScalarSubQuery(op SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("scalar subquery: %w", err)
+		}
+
+		if s == "" {
+			return "", nil, fmt.Errorf("scalar subquery: %w", ErrNoOps)
+		}
+
+		return "(\n" + s + "\n)", vals, nil
+	}
+}
+
+// ScalarOrDefault builds a callback that returns the scalar subquery result,
+// falling back on the provided value if it's NULL or the subquery returns no rows.
+// The default value is bound after the subquery values. Returns `ErrNoOps` if the subquery is empty.
+//
+//	sqld.As(
+//		sqld.ScalarOrDefault(
+//			sqld.New(
+//				sqld.Select(sqld.Sum(sqld.Just("amount"))),
+//				sqld.From(sqld.Just("orders")),
+//				sqld.Where(sqld.ColumnEq("orders.user_id", "users.id")),
+//			),
+//			0,
+//		),
+//		"total",
+//	)
+func ScalarOrDefault(sub SqldFn, dflt driver.Value) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := ScalarSubQuery(sub)()
+		if err != nil {
+			return "", nil, fmt.Errorf("scalar or default: %w", err)
+		}
+
+		return "COALESCE(" + s + ", ?)", append(vals, dflt), nil
+	}
+}
+
 // LeftJoin is a shortcut for `Join()` with `LEFT_JOIN` type
 func LeftJoin(subject SqldFn, op SqldFn) SqldFn {
 	return Join(LEFT_JOIN, subject, op)
@@ -178,13 +662,98 @@ func RightJoin(subject SqldFn, op SqldFn) SqldFn {
 	return Join(RIGHT_JOIN, subject, op)
 }
 
-// ColumnEq builds a callback that returns a comparison statement between two columns
-func ColumnEq(firstColumn string, secondColumn string) SqldFn {
+// LeftJoinFiltered is a shortcut for `LeftJoin()` that places the filter on the joined table
+// in the ON condition, so that the left rows without a match are preserved
+// (which doesn't happen if the filter is placed in the WHERE statement).
+//
+//	sqld.LeftJoinFiltered(sqld.Just("orders"),
+//		sqld.ColumnEq("orders.user_id", "users.id"),
+//		sqld.Eq("orders.status", &status),
+//	)
+func LeftJoinFiltered(subject SqldFn, on SqldFn, rightFilter SqldFn) SqldFn {
+	return LeftJoin(subject, And(on, rightFilter))
+}
+
+// LeftJoinUsing is a shortcut for `JoinUsing()` with `LEFT_JOIN` type
+func LeftJoinUsing(subject SqldFn, columns ...string) SqldFn {
+	return JoinUsing(LEFT_JOIN, subject, columns...)
+}
+
+// RightJoinUsing is a shortcut for `JoinUsing()` with `RIGHT_JOIN` type
+func RightJoinUsing(subject SqldFn, columns ...string) SqldFn {
+	return JoinUsing(RIGHT_JOIN, subject, columns...)
+}
+
+// comparisonOperators are the operators allowed in `ColumnCmp()`
+var comparisonOperators = []string{"=", "<>", "!=", "<", "<=", ">", ">="}
+
+// ColumnCmp builds a callback that returns a comparison statement between two columns.
+// Returns `ErrInvalidOperator` if the operator is not a comparison one (=, <>, !=, <, <=, >, >=).
+//
+//	sqld.ColumnCmp("orders.created_at", ">=", "users.created_at")
+func ColumnCmp(firstColumn string, op string, secondColumn string) SqldFn {
 	return func() (string, []driver.Value, error) {
-		return firstColumn + " = " + secondColumn, nil, nil
+		if !slices.Contains(comparisonOperators, op) {
+			return "", nil, fmt.Errorf("column cmp (%q): %w", op, ErrInvalidOperator)
+		}
+
+		return firstColumn + " " + op + " " + secondColumn, nil, nil
+	}
+}
+
+// Compare builds a callback that compares an expression (e.g. an aggregate) with the provided value.
+// The expression values come before the compared one.
+// Returns `ErrInvalidOperator` if the operator is not a comparison one (=, <>, !=, <, <=, >, >=).
+//
+//	sqld.Having(sqld.Compare(sqld.Count(sqld.Just("id")), ">=", &minOrders)) // HAVING COUNT(id) >= ?
+func Compare[T driver.Value](left SqldFn, op string, val *T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if !slices.Contains(comparisonOperators, op) {
+			return "", nil, fmt.Errorf("compare (%q): %w", op, ErrInvalidOperator)
+		}
+		if val == nil {
+			return "", nil, fmt.Errorf("compare: %w", ErrNilVal)
+		}
+
+		s, vals, err := left()
+		if err != nil {
+			return "", nil, fmt.Errorf("compare: %w", err)
+		}
+
+		return s + " " + op + " ?", append(slices.Clip(vals), val), nil
 	}
 }
 
+// ColumnEq builds a callback that returns a comparison statement between two columns
+func ColumnEq(firstColumn string, secondColumn string) SqldFn {
+	return ColumnCmp(firstColumn, "=", secondColumn)
+}
+
+// ColumnNeq builds a callback that checks if two columns are different
+func ColumnNeq(firstColumn string, secondColumn string) SqldFn {
+	return ColumnCmp(firstColumn, "<>", secondColumn)
+}
+
+// ColumnGt builds a callback that checks if the first column is greater than the second one
+func ColumnGt(firstColumn string, secondColumn string) SqldFn {
+	return ColumnCmp(firstColumn, ">", secondColumn)
+}
+
+// ColumnGte builds a callback that checks if the first column is greater than or equal to the second one
+func ColumnGte(firstColumn string, secondColumn string) SqldFn {
+	return ColumnCmp(firstColumn, ">=", secondColumn)
+}
+
+// ColumnLt builds a callback that checks if the first column is less than the second one
+func ColumnLt(firstColumn string, secondColumn string) SqldFn {
+	return ColumnCmp(firstColumn, "<", secondColumn)
+}
+
+// ColumnLte builds a callback that checks if the first column is less than or equal to the second one
+func ColumnLte(firstColumn string, secondColumn string) SqldFn {
+	return ColumnCmp(firstColumn, "<=", secondColumn)
+}
+
 // Not negates the provided operator.
 func Not(op SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
@@ -201,6 +770,39 @@ func Not(op SqldFn) SqldFn {
 	}
 }
 
+func exists(keyword string, op SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(keyword), err)
+		}
+
+		if s == "" {
+			return "", nil, nil
+		}
+
+		return keyword + " (\n" + s + "\n)", vals, nil
+	}
+}
+
+// Exists builds a callback that checks if the provided subquery returns any row.
+//
+//	sqld.Exists(
+//		sqld.New(
+//			sqld.Select(sqld.Just("1")),
+//			sqld.From(sqld.Just("orders")),
+//			sqld.Where(sqld.ColumnEq("orders.user_id", "users.id")),
+//		),
+//	)
+func Exists(op SqldFn) SqldFn {
+	return exists("EXISTS", op)
+}
+
+// NotExists builds a callback that checks if the provided subquery returns no rows.
+func NotExists(op SqldFn) SqldFn {
+	return exists("NOT EXISTS", op)
+}
+
 // Eq builds a callback that compares a column with the provided value.
 //
 //	sqld.Eq("name", filters.Name)
@@ -210,29 +812,244 @@ func Eq[T driver.Value](columnExpr string, val *T) SqldFn {
 			return "", nil, fmt.Errorf("eq (%s): %w", columnExpr, ErrNilVal)
 		}
 
-		return columnExpr + " = ?", []driver.Value{val}, nil
-	}
+		return columnExpr + " = ?", []driver.Value{val}, nil
+	}
+}
+
+// Like builds a callback that checks if a column text respects the provided pattern.
+//
+//	sqld.Like("name", filters.Name)
+func Like[T driver.Value](columnExpr string, val *T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if val == nil {
+			return "", nil, fmt.Errorf("like (%s): %w", columnExpr, ErrNilVal)
+		}
+
+		return columnExpr + " LIKE ?", []driver.Value{val}, nil
+	}
+}
+
+// LikeEscape is like `Like()`, using escapeChar to escape the wildcards.
+// Use `EscapeLikePattern()` to escape user input.
+//
+//	pattern := "%" + sqld.EscapeLikePattern(filters.Name, '\\') + "%"
+//	sqld.LikeEscape("name", &pattern, '\\')
+func LikeEscape[T driver.Value](columnExpr string, val *T, escapeChar rune) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := Like(columnExpr, val)()
+		if err != nil {
+			return "", nil, err
+		}
+
+		return s + " ESCAPE '" + strings.ReplaceAll(string(escapeChar), "'", "''") + "'", vals, nil
+	}
+}
+
+// Null builds a callback that checks if a column is NULL.
+//
+//	sqld.Null("name")
+func Null(columnExpr string) SqldFn {
+	return is(columnExpr, "NULL")
+}
+
+// NotNull builds a callback that checks if a column is not NULL.
+//
+//	sqld.NotNull("name")
+func NotNull(columnExpr string) SqldFn {
+	return is(columnExpr, "NOT NULL")
+}
+
+// IsTrue builds a callback that checks if a boolean column is TRUE (NULL is not)
+func IsTrue(columnExpr string) SqldFn {
+	return is(columnExpr, "TRUE")
+}
+
+// IsFalse builds a callback that checks if a boolean column is FALSE (NULL is not)
+func IsFalse(columnExpr string) SqldFn {
+	return is(columnExpr, "FALSE")
+}
+
+// IsNotTrue builds a callback that checks if a boolean column is FALSE or NULL
+func IsNotTrue(columnExpr string) SqldFn {
+	return is(columnExpr, "NOT TRUE")
+}
+
+// IsNotFalse builds a callback that checks if a boolean column is TRUE or NULL
+func IsNotFalse(columnExpr string) SqldFn {
+	return is(columnExpr, "NOT FALSE")
+}
+
+func is(columnExpr string, predicate string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return columnExpr + " IS " + predicate, nil, nil
+	}
+}
+
+func distinctFrom[T driver.Value](keyword string, columnExpr string, val *T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if val == nil {
+			return columnExpr + " " + keyword + " NULL", nil, nil
+		}
+
+		return columnExpr + " " + keyword + " ?", []driver.Value{val}, nil
+	}
+}
+
+// DistinctFrom builds a null-safe inequality check between a column and the provided value.
+// Unlike `Eq()`, a nil value is meaningful: it compares the column with NULL.
+//
+//	sqld.DistinctFrom("manager_id", filters.ManagerID)
+func DistinctFrom[T driver.Value](columnExpr string, val *T) SqldFn {
+	return distinctFrom("IS DISTINCT FROM", columnExpr, val)
+}
+
+// NotDistinctFrom builds a null-safe equality check between a column and the provided value.
+// Unlike `Eq()`, a nil value is meaningful: it compares the column with NULL.
+//
+//	sqld.NotDistinctFrom("manager_id", filters.ManagerID)
+func NotDistinctFrom[T driver.Value](columnExpr string, val *T) SqldFn {
+	return distinctFrom("IS NOT DISTINCT FROM", columnExpr, val)
+}
+
+// DateRange builds a callback that checks if a column is in the half-open time range [from, to).
+// A nil bound is omitted, and if both are nil the operator is empty.
+//
+//	sqld.DateRange("created_at", filters.From, filters.To) // created_at >= ? AND created_at < ?
+func DateRange(columnExpr string, from, to *time.Time) SqldFn {
+	return func() (string, []driver.Value, error) {
+		bounds := make([]string, 0, 2)
+		vals := make([]driver.Value, 0, 2)
+
+		if from != nil {
+			bounds = append(bounds, columnExpr+" >= ?")
+			vals = append(vals, *from)
+		}
+		if to != nil {
+			bounds = append(bounds, columnExpr+" < ?")
+			vals = append(vals, *to)
+		}
+
+		if len(bounds) == 0 {
+			return "", nil, nil
+		}
+
+		return strings.Join(bounds, " AND "), vals, nil
+	}
+}
+
+// In builds a callback that checks if a column value is contained in the provided slice of values.
+//
+//	sqld.In("pizzas", filters.Pizzas)
+func In[T driver.Value](columnExpr string, vals *[]T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if vals == nil {
+			return "", nil, fmt.Errorf("in (%s): %w", columnExpr, ErrNilVal)
+		}
+
+		if len(*vals) == 0 {
+			return "", nil, nil
+		}
+
+		return columnExpr + " IN (" + placeholders(len(*vals)) + ")", mapSlice(*vals), nil
+	}
+}
+
+// NotIn builds a callback that checks if a column value is not contained in the provided slice of values.
+//
+//	sqld.NotIn("pizzas", filters.Pizzas)
+func NotIn[T driver.Value](columnExpr string, vals []T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(vals) == 0 {
+			return "", nil, nil
+		}
+
+		return columnExpr + " NOT IN (" + placeholders(len(vals)) + ")", mapSlice(vals), nil
+	}
+}
+
+func inSubQuery(keyword string, columnExpr string, sub SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := sub()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s (%s): %w", strings.ToLower(keyword), columnExpr, err)
+		}
+
+		if s == "" {
+			return "", nil, nil
+		}
+
+		return columnExpr + " " + keyword + " (\n" + s + "\n)", vals, nil
+	}
+}
+
+// InSubQuery builds a callback that checks if a column value is contained in the results of the subquery.
+// If the subquery is empty, the filter is skipped.
+//
+//	sqld.InSubQuery("users.id",
+//		sqld.New(
+//			sqld.Select(sqld.Just("user_id")),
+//			sqld.From(sqld.Just("orders")),
+//			sqld.Where(sqld.Eq("status", &status)),
+//		),
+//	)
+func InSubQuery(columnExpr string, sub SqldFn) SqldFn {
+	return inSubQuery("IN", columnExpr, sub)
+}
+
+// NotInSubQuery builds a callback that checks if a column value is not contained in the results of the subquery.
+// If the subquery is empty, the filter is skipped.
+func NotInSubQuery(columnExpr string, sub SqldFn) SqldFn {
+	return inSubQuery("NOT IN", columnExpr, sub)
 }
 
-// Eq builds a callback that checks if a column is NULL.
+// TupleIn builds a callback that checks if the tuple of columns is contained in the provided rows.
+// Returns `ErrNoColumns` if no columns are provided,
+// and `ErrColumnCountMismatch` if a row length differs from the columns count.
 //
-//	sqld.Null("name")
-func Null(columnExpr string) SqldFn {
+//	sqld.TupleIn([]string{"order_id", "line"},
+//		[]driver.Value{1, 1},
+//		[]driver.Value{1, 2},
+//	) // (order_id, line) IN ((?, ?), (?, ?))
+func TupleIn(columns []string, rows ...[]driver.Value) SqldFn {
 	return func() (string, []driver.Value, error) {
-		return columnExpr + " IS NULL", nil, nil
+		if len(columns) == 0 {
+			return "", nil, fmt.Errorf("tuple in: %w", ErrNoColumns)
+		}
+
+		if len(rows) == 0 {
+			return "", nil, nil
+		}
+
+		tuples, vals := make([]string, 0, len(rows)), make([]driver.Value, 0, len(rows)*len(columns))
+		for i, row := range rows {
+			if len(row) != len(columns) {
+				return "", nil, fmt.Errorf("tuple in (row %d): %w", i, ErrColumnCountMismatch)
+			}
+
+			tuples = append(tuples, "("+placeholders(len(row))+")")
+			vals = append(vals, row...)
+		}
+
+		return "(" + strings.Join(columns, ", ") + ") IN (" + strings.Join(tuples, ", ") + ")", vals, nil
 	}
 }
 
-// In builds a callback that checks if a column value is contained in the provided slice of values.
+// InLower builds a callback that checks if a column value is contained in the provided slice of values,
+// ignoring the casing: the column is wrapped in LOWER() and the values are lower-cased.
 //
-//	sqld.In("pizzas", filters.Pizzas)
-func In[T driver.Value](columnExpr string, vals *[]T) SqldFn {
+//	sqld.InLower("email", filters.Emails)
+func InLower(columnExpr string, vals []string) SqldFn {
 	return func() (string, []driver.Value, error) {
-		if len(*vals) == 0 {
+		if len(vals) == 0 {
 			return "", nil, nil
 		}
 
-		return columnExpr + " IN (" + strings.Repeat(", ?", len(*vals))[1:] + ")", mapSlice(*vals), nil
+		lowered := make([]driver.Value, 0, len(vals))
+		for _, val := range vals {
+			lowered = append(lowered, strings.ToLower(val))
+		}
+
+		return "LOWER(" + columnExpr + ") IN (" + placeholders(len(vals)) + ")", lowered, nil
 	}
 }
 
@@ -245,47 +1062,33 @@ const (
 
 func boolCond(cond Condition, ops ...SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
-		if len(ops) == 0 {
-			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(string(cond)), ErrNoOps)
+		s, vals, err := RawCond(cond, ops...)()
+		if err != nil || s == "" {
+			return "", nil, err
 		}
 
-		var sb strings.Builder
-		vals := make([]driver.Value, 0, len(ops))
-		var errs error
-
-		atLeastOne := false
-		for _, fn := range ops {
-			s, fnVals, err := fn()
-			if err != nil {
-				errs = errors.Join(errs, err)
-			}
-
-			if errs != nil || s == "" {
-				continue
-			}
-
-			if atLeastOne {
-				sb.WriteString(string(cond) + " ")
-			}
-			sb.WriteString(s)
-			sb.WriteRune('\n')
-
-			if len(fnVals) != 0 {
-				vals = append(vals, fnVals...)
-			}
+		return "(" + s + "\n)", vals, nil
+	}
+}
 
-			atLeastOne = true
+// RawCond is like `And()`/`Or()`, without wrapping the conditions in parentheses.
+// Use it only when the enclosing clause doesn't combine it with other conditions.
+func RawCond(cond Condition, ops ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(ops) == 0 {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(string(cond)), ErrNoOps)
 		}
 
+		parts, size, vals, errs := runOps(ops)
 		if errs != nil {
 			return "", nil, fmt.Errorf("%s: %w", cond, errs)
 		}
 
-		if !atLeastOne {
+		if len(parts) == 0 {
 			return "", nil, nil
 		}
 
-		return "(" + sb.String() + ")", vals, nil
+		return joinParts("", parts, size, "\n"+string(cond)+" ", ""), vals, nil
 	}
 }
 
@@ -335,38 +1138,39 @@ func Where(ops ...SqldFn) SqldFn {
 			return "", nil, fmt.Errorf("where: %w", ErrNoOps)
 		}
 
-		var sb strings.Builder
-		vals := make([]driver.Value, 0, len(ops))
-		var errs error
-
-		for _, fn := range ops {
-			s, fnVals, err := fn()
-			if err != nil {
-				errs = errors.Join(errs, err)
-			}
-
-			if errs != nil || s == "" {
-				continue
-			}
-
-			sb.WriteString("\t" + s)
-			sb.WriteRune('\n')
+		parts, size, vals, errs := runOps(ops)
+		if errs != nil {
+			return "", nil, fmt.Errorf("where:\n\t\t%w", errs)
+		}
 
-			if len(fnVals) != 0 {
-				vals = append(vals, fnVals...)
-			}
+		if len(parts) == 0 {
+			return "", nil, nil
 		}
 
+		return joinParts("WHERE\n\t", parts, size, "\n\t", "\n"), vals, nil
+	}
+}
+
+// WhereOrTrue is like `Where()`, but returns a WHERE TRUE statement instead of an empty string
+// when all the operators are empty, so the query always has a WHERE to append conditions to.
+//
+//	sqld.WhereOrTrue(
+//		sqld.IfNotNil(filters.Name,
+//			sqld.Eq("name", filters.Name),
+//		),
+//	)
+func WhereOrTrue(ops ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		parts, size, vals, errs := runOps(ops)
 		if errs != nil {
 			return "", nil, fmt.Errorf("where:\n\t\t%w", errs)
 		}
 
-		s := sb.String()
-		if s == "" {
-			return "", nil, nil
+		if len(parts) == 0 {
+			return "WHERE\n\tTRUE\n", nil, nil
 		}
 
-		return "WHERE\n" + sb.String(), vals, nil
+		return joinParts("WHERE\n\t", parts, size, "\n\t", "\n"), vals, nil
 	}
 }
 
@@ -383,45 +1187,21 @@ func OrderBy(ops ...SqldFn) SqldFn {
 			return "", nil, fmt.Errorf("orderBy: %w", ErrNoOps)
 		}
 
-		var sb strings.Builder
-		vals := make([]driver.Value, 0)
-		var errs error
-
-		atLeastOne := false
-		for _, fn := range ops {
-			s, fnVals, err := fn()
-			if err != nil {
-				errs = errors.Join(errs, err)
-			}
-
-			if errs != nil || s == "" {
-				continue
-			}
-
-			if atLeastOne {
-				sb.WriteString(",\n\t")
-			}
-			sb.WriteString(s)
-
-			if len(fnVals) != 0 {
-				vals = append(vals, fnVals...)
-			}
-
-			atLeastOne = true
-		}
-
+		parts, size, vals, errs := runOps(ops)
 		if errs != nil {
 			return "", nil, fmt.Errorf("orderBy:\n\t\t%w", errs)
 		}
 
-		if !atLeastOne {
+		if len(parts) == 0 {
 			return "", nil, nil
 		}
 
-		return "ORDER BY\n" + sb.String(), vals, nil
+		return joinParts("ORDER BY\n", parts, size, ",\n\t", ""), vals, nil
 	}
 }
 
+// SortingOrder is the direction of a sorting. It can be chosen at runtime (e.g. from an API parameter),
+// since `Sort()` checks it against the known constants.
 type SortingOrder string
 
 const (
@@ -429,9 +1209,21 @@ const (
 	DESC SortingOrder = "DESC"
 )
 
+// Valid reports whether the order is one of the known constants
+func (order SortingOrder) Valid() bool {
+	return order == ASC || order == DESC
+}
+
 // Sort builds a callback used to specify the sorting in `OrderBy()`.
+// Returns `ErrInvalidSortingOrder` if the order is not one of the known constants.
+//
+//	sqld.OrderBy(sqld.Sort(sqld.SortingOrder(params.Order), "created_at"))
 func Sort(order SortingOrder, columnExpr string) SqldFn {
 	return func() (string, []driver.Value, error) {
+		if !order.Valid() {
+			return "", nil, fmt.Errorf("sort (%q): %w", order, ErrInvalidSortingOrder)
+		}
+
 		return columnExpr + " " + string(order), nil, nil
 	}
 }
@@ -446,6 +1238,89 @@ func Desc(columnExpr string) SqldFn {
 	return Sort(DESC, columnExpr)
 }
 
+// NullsOrder is the position of NULLs in a sorting
+type NullsOrder string
+
+const (
+	NULLS_FIRST NullsOrder = "NULLS FIRST"
+	NULLS_LAST  NullsOrder = "NULLS LAST"
+)
+
+// SortNulls is like `Sort()`, specifying the position of NULLs.
+// Returns `ErrInvalidSortingOrder` if the order or the NULLs position are not one of the known constants.
+//
+//	sqld.OrderBy(sqld.SortNulls(sqld.DESC, sqld.NULLS_LAST, "published_at"))
+func SortNulls(order SortingOrder, nulls NullsOrder, columnExpr string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if nulls != NULLS_FIRST && nulls != NULLS_LAST {
+			return "", nil, fmt.Errorf("sort (%q): %w", nulls, ErrInvalidSortingOrder)
+		}
+
+		s, vals, err := Sort(order, columnExpr)()
+		if err != nil {
+			return "", nil, err
+		}
+
+		return s + " " + string(nulls), vals, nil
+	}
+}
+
+// AscNullsFirst builds a callback used to specify an ascending sorting with NULLs first in `OrderBy()`
+func AscNullsFirst(columnExpr string) SqldFn {
+	return SortNulls(ASC, NULLS_FIRST, columnExpr)
+}
+
+// AscNullsLast builds a callback used to specify an ascending sorting with NULLs last in `OrderBy()`
+func AscNullsLast(columnExpr string) SqldFn {
+	return SortNulls(ASC, NULLS_LAST, columnExpr)
+}
+
+// DescNullsFirst builds a callback used to specify a descending sorting with NULLs first in `OrderBy()`
+func DescNullsFirst(columnExpr string) SqldFn {
+	return SortNulls(DESC, NULLS_FIRST, columnExpr)
+}
+
+// DescNullsLast builds a callback used to specify a descending sorting with NULLs last in `OrderBy()`
+func DescNullsLast(columnExpr string) SqldFn {
+	return SortNulls(DESC, NULLS_LAST, columnExpr)
+}
+
+// SafeSort builds a callback used to specify the sorting in `OrderBy()`, checking the column
+// against a whitelist. Use it when the column comes from user input.
+// Returns `ErrColumnNotAllowed` if the column is not in the whitelist,
+// and `ErrInvalidSortingOrder` if the order is not one of the known constants.
+//
+//	sort, err := sqld.SafeSort([]string{"name", "created_at"}, params.SortBy, sqld.DESC)
+func SafeSort(allowed []string, column string, order SortingOrder) (SqldFn, error) {
+	if !slices.Contains(allowed, column) {
+		return nil, fmt.Errorf("sort (%s): %w", column, ErrColumnNotAllowed)
+	}
+	if !order.Valid() {
+		return nil, fmt.Errorf("sort (%q): %w", order, ErrInvalidSortingOrder)
+	}
+
+	return Sort(order, column), nil
+}
+
+// MustSafeSort is like `SafeSort()`, but panics if the column is not in the whitelist or the order is invalid
+func MustSafeSort(allowed []string, column string, order SortingOrder) SqldFn {
+	sort, err := SafeSort(allowed, column, order)
+	if err != nil {
+		panic(err)
+	}
+
+	return sort
+}
+
+// SafeSortFor is like `SafeSort()`, using the `Model` columns as whitelist.
+// The column can be qualified with `Model.TableName()`; the resulting sorting always is.
+func SafeSortFor[M Model](column string, order SortingOrder) (SqldFn, error) {
+	var model M
+	column = strings.TrimPrefix(column, model.TableName()+".")
+
+	return SafeSort(TableColumns[M](), model.TableName()+"."+column, order)
+}
+
 // Having builds a callback combining all the operators in a HAVING statement.
 //
 //	sqld.Having(
@@ -464,83 +1339,99 @@ func Having(ops ...SqldFn) SqldFn {
 			return "", nil, fmt.Errorf("having: %w", ErrNoOps)
 		}
 
-		var sb strings.Builder
-		vals := make([]driver.Value, 0, len(ops))
-		var errs error
-
-		for _, fn := range ops {
-			s, fnVals, err := fn()
-			if err != nil {
-				errs = errors.Join(errs, err)
-			}
-
-			if errs != nil || s == "" {
-				continue
-			}
-
-			sb.WriteString("\t" + s)
-			sb.WriteRune('\n')
-
-			if len(fnVals) != 0 {
-				vals = append(vals, fnVals...)
-			}
-		}
-
+		parts, size, vals, errs := runOps(ops)
 		if errs != nil {
 			return "", nil, fmt.Errorf("having:\n\t\t%w", errs)
 		}
 
-		s := sb.String()
-		if s == "" {
+		if len(parts) == 0 {
 			return "", nil, nil
 		}
 
-		return "HAVING\n" + s, vals, nil
+		return joinParts("HAVING\n\t", parts, size, "\n\t", "\n"), vals, nil
 	}
 }
 
+// HavingCount builds a callback that returns a HAVING statement comparing `COUNT(*)` with the provided value.
+//
+//	sqld.HavingCount(">=", &minOrders) // HAVING COUNT(*) >= ?
+func HavingCount[T driver.Value](op string, val *T) SqldFn {
+	return Having(Compare(Count(AllWildcard()), op, val))
+}
+
+// HavingSum builds a callback that returns a HAVING statement comparing the sum of the column with the provided value.
+//
+//	sqld.HavingSum("orders.total", ">", &minTotal) // HAVING SUM(orders.total) > ?
+func HavingSum[T driver.Value](columnExpr string, op string, val *T) SqldFn {
+	return Having(Compare(Sum(Just(columnExpr)), op, val))
+}
+
+// GroupBy builds a callback combining all the operators in a GROUP BY statement.
+// Like `OrderBy()`, empty operators are dropped (the statement too, if they're all empty)
+// and their values are propagated in order.
+//
+//	sqld.GroupBy(
+//		sqld.Just("country"),
+//		sqld.IfTrue(filters.ByCity, sqld.Just("city")),
+//	)
 func GroupBy(ops ...SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
 		if len(ops) == 0 {
 			return "", nil, fmt.Errorf("groupBy: %w", ErrNoOps)
 		}
 
-		var sb strings.Builder
-		vals := make([]driver.Value, 0)
-		var errs error
+		parts, size, vals, errs := runOps(ops)
+		if errs != nil {
+			return "", nil, fmt.Errorf("groupBy:\n\t\t%w", errs)
+		}
 
-		atLeastOne := false
-		for _, fn := range ops {
-			s, fnVals, err := fn()
-			if err != nil {
-				errs = errors.Join(errs, err)
-			}
+		if len(parts) == 0 {
+			return "", nil, nil
+		}
 
-			if errs != nil || s == "" {
-				continue
-			}
+		return joinParts("GROUP BY\n", parts, size, ",\n\t", ""), vals, nil
+	}
+}
 
-			if atLeastOne {
-				sb.WriteString(",\n\t")
-			}
-			sb.WriteString(s)
+func groupingSet(name string, columns ...string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(columns) == 0 {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(name), ErrNoColumns)
+		}
 
-			if len(fnVals) != 0 {
-				vals = append(vals, fnVals...)
-			}
+		return name + "(" + strings.Join(columns, ", ") + ")", nil, nil
+	}
+}
 
-			atLeastOne = true
-		}
+// Rollup builds a callback used to group by all the prefixes of the columns in `GroupBy()`,
+// producing subtotals for each level of the hierarchy
+//
+//	sqld.GroupBy(sqld.Rollup("country", "city")) // GROUP BY ROLLUP(country, city)
+func Rollup(columns ...string) SqldFn {
+	return groupingSet("ROLLUP", columns...)
+}
 
-		if errs != nil {
-			return "", nil, fmt.Errorf("groupBy:\n\t\t%w", errs)
+// Cube builds a callback used to group by all the combinations of the columns in `GroupBy()`
+func Cube(columns ...string) SqldFn {
+	return groupingSet("CUBE", columns...)
+}
+
+// GroupingSets builds a callback used to group by each of the provided column sets in `GroupBy()`.
+// An empty set produces the grand total.
+//
+//	sqld.GroupBy(sqld.GroupingSets([]string{"country", "city"}, []string{"country"}, nil))
+func GroupingSets(sets ...[]string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(sets) == 0 {
+			return "", nil, fmt.Errorf("grouping sets: %w", ErrNoColumns)
 		}
 
-		if !atLeastOne {
-			return "", nil, nil
+		groups := make([]string, 0, len(sets))
+		for _, set := range sets {
+			groups = append(groups, "("+strings.Join(set, ", ")+")")
 		}
 
-		return "GROUP BY\n" + sb.String(), vals, nil
+		return "GROUPING SETS(" + strings.Join(groups, ", ") + ")", nil, nil
 	}
 }
 
@@ -563,3 +1454,181 @@ func Offset(skip *uint) SqldFn {
 		return "OFFSET ?", []driver.Value{*skip}, nil
 	}
 }
+
+// LimitInt is like `Limit()`, for signed counts: a negative count is a no-op,
+// instead of wrapping around to a huge unsigned one
+func LimitInt(count *int) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if count == nil || *count < 0 {
+			return "", nil, nil
+		}
+
+		return "LIMIT ?", []driver.Value{*count}, nil
+	}
+}
+
+// OffsetInt is like `Offset()`, for signed counts: a negative count is a no-op,
+// instead of wrapping around to a huge unsigned one
+func OffsetInt(skip *int) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if skip == nil || *skip < 0 {
+			return "", nil, nil
+		}
+
+		return "OFFSET ?", []driver.Value{*skip}, nil
+	}
+}
+
+// Paginate builds a callback that returns the LIMIT and OFFSET statements for the provided page (starting from 1).
+// A page lower than 1 is treated as the first one, and a zero size is a no-op.
+//
+//	sqld.Paginate(filters.Page, 20) // LIMIT ? OFFSET ?
+func Paginate(page, size uint) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if size == 0 {
+			return "", nil, nil
+		}
+
+		offset := uint(0)
+		if page > 1 {
+			offset = (page - 1) * size
+		}
+
+		limit, limitVals, err := Limit(&size)()
+		if err != nil {
+			return "", nil, fmt.Errorf("paginate: %w", err)
+		}
+
+		skip, skipVals, err := Offset(&offset)()
+		if err != nil {
+			return "", nil, fmt.Errorf("paginate: %w", err)
+		}
+
+		return limit + " " + skip, append(limitVals, skipVals...), nil
+	}
+}
+
+type LockStrength string
+
+const (
+	LOCK_UPDATE        LockStrength = "UPDATE"
+	LOCK_NO_KEY_UPDATE LockStrength = "NO KEY UPDATE"
+	LOCK_SHARE         LockStrength = "SHARE"
+	LOCK_KEY_SHARE     LockStrength = "KEY SHARE"
+)
+
+type LockWait string
+
+const (
+	LOCK_WAIT        LockWait = ""
+	LOCK_NOWAIT      LockWait = "NOWAIT"
+	LOCK_SKIP_LOCKED LockWait = "SKIP LOCKED"
+)
+
+// Lock builds a callback that returns a row-locking clause with the given strength and waiting policy.
+// Use this as the last operator of the query, after ORDER BY/LIMIT/OFFSET.
+func Lock(strength LockStrength, wait LockWait) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if wait == LOCK_WAIT {
+			return "FOR " + string(strength), nil, nil
+		}
+
+		return "FOR " + string(strength) + " " + string(wait), nil, nil
+	}
+}
+
+// ForUpdate is a shortcut for `Lock()` with `LOCK_UPDATE` strength
+func ForUpdate() SqldFn {
+	return Lock(LOCK_UPDATE, LOCK_WAIT)
+}
+
+// ForUpdateSkipLocked is a shortcut for `Lock()` with `LOCK_UPDATE` strength,
+// skipping the rows that are already locked
+func ForUpdateSkipLocked() SqldFn {
+	return Lock(LOCK_UPDATE, LOCK_SKIP_LOCKED)
+}
+
+// ForUpdateNoWait is a shortcut for `Lock()` with `LOCK_UPDATE` strength,
+// failing instead of waiting if a row is already locked
+func ForUpdateNoWait() SqldFn {
+	return Lock(LOCK_UPDATE, LOCK_NOWAIT)
+}
+
+// ForShare is a shortcut for `Lock()` with `LOCK_SHARE` strength
+func ForShare() SqldFn {
+	return Lock(LOCK_SHARE, LOCK_WAIT)
+}
+
+// ForNoKeyUpdate is a shortcut for `Lock()` with `LOCK_NO_KEY_UPDATE` strength:
+// it locks the rows without blocking inserts referencing them through foreign keys
+func ForNoKeyUpdate() SqldFn {
+	return Lock(LOCK_NO_KEY_UPDATE, LOCK_WAIT)
+}
+
+// ForKeyShare is a shortcut for `Lock()` with `LOCK_KEY_SHARE` strength:
+// it only blocks deletes and key updates on the rows
+func ForKeyShare() SqldFn {
+	return Lock(LOCK_KEY_SHARE, LOCK_WAIT)
+}
+
+// countTail returns the offset of the top-level ORDER BY/LIMIT/OFFSET tail of the query,
+// or the length of the query if there's none
+func countTail(query string) int {
+	depth := 0
+	indexes := codeIndexes(query, func(i int) bool {
+		switch query[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+
+		if depth != 0 || i > 0 && !unicode.IsSpace(rune(query[i-1])) {
+			return false
+		}
+
+		for _, keyword := range []string{"ORDER BY", "LIMIT", "OFFSET"} {
+			end := i + len(keyword)
+			if end <= len(query) && strings.EqualFold(query[i:end], keyword) &&
+				(end == len(query) || unicode.IsSpace(rune(query[end]))) {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	if len(indexes) == 0 {
+		return len(query)
+	}
+
+	return indexes[0]
+}
+
+// CountQuery builds a callback that counts the rows returned by the provided query,
+// wrapping it in a `SELECT COUNT(*)`. The ORDER BY, LIMIT and OFFSET statements
+// (and their values) are stripped from the query, since they're meaningless for counting.
+//
+//	query := sqld.New(
+//		sqld.Select(sqld.AllWildcard()),
+//		sqld.From(sqld.Just("pizzas")),
+//		sqld.Where(sqld.Eq("name", filters.Name)),
+//		sqld.OrderBy(sqld.Asc("name")),
+//		sqld.Limit(filters.Limit),
+//	)
+//	count := sqld.CountQuery(query)
+func CountQuery(op SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("count query: %w", err)
+		}
+
+		s = strings.TrimRight(s[:countTail(s)], "\n")
+		if count := CountPlaceholders(s); count < len(vals) {
+			vals = vals[:count]
+		}
+
+		return "SELECT COUNT(*)\nFROM (\n" + s + "\n) AS _count", vals, nil
+	}
+}
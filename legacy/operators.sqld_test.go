@@ -0,0 +1,1023 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotIn(t *testing.T) {
+	s, vals, err := NotIn("pizzas", []string{})()
+	if s != "" || vals != nil || err != nil {
+		t.Fatalf("empty slice should be a no-op, got %q %v %v", s, vals, err)
+	}
+
+	s, vals, err = NotIn("pizzas", []string{"margherita", "diavola", "4 stagioni"})()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "pizzas NOT IN (?, ?, ?)" {
+		t.Fatalf("wrong NOT IN: %s", s)
+	}
+	if len(vals) != 3 || vals[0] != "margherita" || vals[2] != "4 stagioni" {
+		t.Fatalf("wrong values: %v", vals)
+	}
+}
+
+func TestExists(t *testing.T) {
+	status := "paid"
+	s, vals, err := And(
+		Exists(
+			New(
+				Select(Just("1")),
+				From(Just("orders")),
+				Where(
+					And(
+						ColumnEq("orders.user_id", "users.id"),
+						Eq("orders.status", &status),
+					),
+				),
+			),
+		),
+		NotExists(NoOp),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(EXISTS (\nSELECT\n\t1\nFROM orders\nWHERE\n\t(orders.user_id = users.id\nAND orders.status = ?\n)\n\n\n)\n)"
+	if s != expected {
+		t.Fatalf("wrong EXISTS:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 1 || vals[0] != &status {
+		t.Fatalf("inner values should flow through: %v", vals)
+	}
+}
+
+func TestCase(t *testing.T) {
+	low, high := 10, 100
+	s, vals, err := Select(
+		As(
+			Case(
+				When(Eq("amount", &high), Val("high")),
+				When(Eq("amount", &low), Val("low")),
+				Else(Val("other")),
+			),
+			"bucket",
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "SELECT\n\tCASE WHEN amount = ? THEN ? WHEN amount = ? THEN ? ELSE ? END AS bucket" {
+		t.Fatalf("wrong CASE: %q", s)
+	}
+
+	expected := []driver.Value{&high, "high", &low, "low", "other"}
+	if len(vals) != len(expected) {
+		t.Fatalf("wrong values: %v", vals)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("wrong value ordering at %d: %v", i, vals)
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	customer, supplier := "Mario", "Luigi"
+	query := func(table string, name *string) SqldFn {
+		return New(
+			Select(Just("name")),
+			From(Just(table)),
+			Where(Eq("name", name)),
+		)
+	}
+
+	s, vals, err := UnionAll(
+		query("customers", &customer),
+		NoOp,
+		query("suppliers", &supplier),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT\n\tname\nFROM customers\nWHERE\n\tname = ?\n\n" +
+		"\nUNION ALL\n" +
+		"SELECT\n\tname\nFROM suppliers\nWHERE\n\tname = ?\n\n"
+	if s != expected {
+		t.Fatalf("wrong UNION ALL:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 2 || vals[0] != &customer || vals[1] != &supplier {
+		t.Fatalf("wrong values ordering: %v", vals)
+	}
+
+	s, vals, err = Union(NoOp, query("customers", &customer))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(s, "UNION") || len(vals) != 1 {
+		t.Fatalf("single query should not be combined: %q", s)
+	}
+}
+
+func TestIntersectExcept(t *testing.T) {
+	first, second, third := 1, 2, 3
+	query := func(id *int) SqldFn {
+		return New(Select(Just("id")), From(Just("t")), Where(Eq("id", id)))
+	}
+	operand := "SELECT\n\tid\nFROM t\nWHERE\n\tid = ?\n\n"
+
+	for keyword, op := range map[string]func(...SqldFn) SqldFn{
+		"INTERSECT":     Intersect,
+		"INTERSECT ALL": IntersectAll,
+		"EXCEPT":        Except,
+		"EXCEPT ALL":    ExceptAll,
+	} {
+		s, vals, err := op(query(&first), query(&second), query(&third))()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := operand + "\n" + keyword + "\n" + operand + "\n" + keyword + "\n" + operand
+		if s != expected {
+			t.Fatalf("wrong %s:\n%q\n%q", keyword, s, expected)
+		}
+		if len(vals) != 3 || vals[0] != &first || vals[1] != &second || vals[2] != &third {
+			t.Fatalf("wrong %s values ordering: %v", keyword, vals)
+		}
+	}
+}
+
+func TestLock(t *testing.T) {
+	var limit uint = 10
+	for expected, lock := range map[string]SqldFn{
+		"FOR UPDATE":               ForUpdate(),
+		"FOR UPDATE SKIP LOCKED":   ForUpdateSkipLocked(),
+		"FOR UPDATE NOWAIT":        ForUpdateNoWait(),
+		"FOR SHARE":                ForShare(),
+		"FOR NO KEY UPDATE":        ForNoKeyUpdate(),
+		"FOR KEY SHARE":            ForKeyShare(),
+		"FOR SHARE SKIP LOCKED":    Lock(LOCK_SHARE, LOCK_SKIP_LOCKED),
+		"FOR NO KEY UPDATE NOWAIT": Lock(LOCK_NO_KEY_UPDATE, LOCK_NOWAIT),
+	} {
+		s, vals, err := New(
+			Select(AllWildcard()),
+			From(Just("jobs")),
+			OrderBy(Asc("id")),
+			Limit(&limit),
+			lock,
+		)()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if s != "SELECT\n\t*\nFROM jobs\nORDER BY\nid ASC\nLIMIT ?\n"+expected+"\n" {
+			t.Fatalf("wrong locking clause: %q", s)
+		}
+		if len(vals) != 1 {
+			t.Fatalf("locking clause should not bind values: %v", vals)
+		}
+	}
+}
+
+func TestLeftJoinFiltered(t *testing.T) {
+	status := "paid"
+	s, vals, err := LeftJoinFiltered(
+		SubQuery(New(Select(AllWildcard()), From(Just("orders")), Where(Eq("store", &status))), "o"),
+		ColumnEq("o.user_id", "users.id"),
+		Eq("o.status", &status),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "LEFT JOIN (\nSELECT\n\t*\nFROM orders\nWHERE\n\tstore = ?\n\n\n) AS o ON (o.user_id = users.id\nAND o.status = ?\n)"
+	if s != expected {
+		t.Fatalf("wrong filtered LEFT JOIN:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 2 || vals[0] != &status || vals[1] != &status {
+		t.Fatalf("values should be flattened in subject, condition order: %v", vals)
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	s, _, err := Select(
+		As(Sum(Just("amount")), "total"),
+		As(Avg(Just("amount")), "average"),
+		Min(Just("amount")),
+		Max(Just("amount")),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "SELECT\n\tSUM(amount) AS total,\n\tAVG(amount) AS average,\n\tMIN(amount),\n\tMAX(amount)" {
+		t.Fatalf("wrong aggregates: %q", s)
+	}
+
+	var amount *int
+	_, _, err = Sum(Eq("amount", amount))()
+	if !errors.Is(err, ErrNilVal) || !strings.HasPrefix(err.Error(), "sum: ") {
+		t.Fatalf("nil value error should be propagated, got %v", err)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	s, _, err := Select(As(Coalesce(Just("nickname"), "'anonymous'"), "name"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "SELECT\n\tCOALESCE(nickname, 'anonymous') AS name" {
+		t.Fatalf("wrong COALESCE: %q", s)
+	}
+
+	s, vals, err := CoalesceOps(Val("first"), Just("name"), NoOp, Val("last"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "COALESCE(?, name, ?)" || len(vals) != 2 || vals[0] != "first" || vals[1] != "last" {
+		t.Fatalf("wrong multi-expression COALESCE: %q %v", s, vals)
+	}
+}
+
+func TestInLower(t *testing.T) {
+	s, vals, err := InLower("email", []string{"Mario@Example.com", "luigi@example.com"})()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "LOWER(email) IN (?, ?)" {
+		t.Fatalf("wrong case-insensitive IN: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != "mario@example.com" || vals[1] != "luigi@example.com" {
+		t.Fatalf("values should be lower-cased: %v", vals)
+	}
+
+	s, vals, err = InLower("email", nil)()
+	if s != "" || vals != nil || err != nil {
+		t.Fatal("empty slice should be a no-op")
+	}
+}
+
+func TestScalarOrDefault(t *testing.T) {
+	status := "paid"
+	sub := New(
+		Select(Sum(Just("amount"))),
+		From(Just("orders")),
+		Where(Eq("status", &status)),
+	)
+	inner := "SELECT\n\tSUM(amount)\nFROM orders\nWHERE\n\tstatus = ?\n\n"
+
+	s, vals, err := ScalarOrDefault(sub, 0)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "COALESCE((\n"+inner+"\n), ?)" {
+		t.Fatalf("wrong scalar default: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != &status || vals[1] != driver.Value(0) {
+		t.Fatalf("default should be bound after subquery values: %v", vals)
+	}
+
+	s, vals, err = CoalesceOps(ScalarSubQuery(sub), Val(0))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "COALESCE((\n"+inner+"\n), ?)" || len(vals) != 2 || vals[0] != &status {
+		t.Fatalf("wrong coalesced scalar subquery: %q %v", s, vals)
+	}
+
+	var name *string
+	_, _, err = ScalarOrDefault(IfNotNil(name, Eq("name", name)), 0)()
+	if !errors.Is(err, ErrNoOps) || !strings.HasPrefix(err.Error(), "scalar or default: scalar subquery:") {
+		t.Fatalf("expected no ops error, got %v", err)
+	}
+	if _, _, err = ScalarSubQuery(NoOp)(); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no ops error, got %v", err)
+	}
+}
+
+func TestSafeSort(t *testing.T) {
+	allowed := []string{"name", "created_at"}
+
+	sort, err := SafeSort(allowed, "created_at", DESC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _, _ := sort(); s != "created_at DESC" {
+		t.Fatalf("wrong safe sort: %q", s)
+	}
+
+	if _, err = SafeSort(allowed, "name; DROP TABLE users", ASC); !errors.Is(err, ErrColumnNotAllowed) {
+		t.Fatalf("expected column not allowed error, got %v", err)
+	}
+
+	sort, err = SafeSortFor[testModel]("nameddd", ASC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _, _ := sort(); s != "TestModel.nameddd ASC" {
+		t.Fatalf("wrong model safe sort: %q", s)
+	}
+
+	if _, err = SafeSortFor[testModel]("Named", ASC); !errors.Is(err, ErrColumnNotAllowed) {
+		t.Fatalf("expected column not allowed error for model, got %v", err)
+	}
+}
+
+func TestVal(t *testing.T) {
+	s, vals, err := Greatest(Just("x"), Val(0))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "GREATEST(x, ?)" || len(vals) != 1 || vals[0] != driver.Value(0) {
+		t.Fatalf("wrong clamp: %q %v", s, vals)
+	}
+
+	ceiling := 100
+	s, vals, err = Least(Just("x"), ValPtr(&ceiling))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "LEAST(x, ?)" || len(vals) != 1 || vals[0] != 100 {
+		t.Fatalf("wrong pointer clamp: %q %v", s, vals)
+	}
+
+	var floor *int
+	s, vals, err = Greatest(Just("x"), ValPtr(floor))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "GREATEST(x)" || len(vals) != 0 {
+		t.Fatalf("nil pointer should be skipped: %q %v", s, vals)
+	}
+}
+
+func TestValLit(t *testing.T) {
+	s, vals, err := Val("O'Reilly")()
+	if err != nil || s != "?" || len(vals) != 1 || vals[0] != "O'Reilly" {
+		t.Fatalf("wrong bound value: %q %v %v", s, vals, err)
+	}
+
+	s, vals, err = CoalesceOps(Just("nickname"), Lit("'anonymous'"), Val("fallback"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "COALESCE(nickname, 'anonymous', ?)" || len(vals) != 1 || vals[0] != "fallback" {
+		t.Fatalf("literal should be rendered as is: %q %v", s, vals)
+	}
+}
+
+func TestCountQuery(t *testing.T) {
+	name := "test"
+	var limit, offset uint = 10, 20
+	s, vals, err := CountQuery(New(
+		Select(Just("name"), Just("(SELECT id FROM t ORDER BY id LIMIT 1)")),
+		From(Just("pizzas")),
+		Where(Eq("name", &name)),
+		OrderBy(Asc("name")),
+		Limit(&limit),
+		Offset(&offset),
+	))()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT COUNT(*)\nFROM (\nSELECT\n\tname,\n\t(SELECT id FROM t ORDER BY id LIMIT 1)\nFROM pizzas\nWHERE\n\tname = ?\n) AS _count"
+	if s != expected {
+		t.Fatalf("wrong count query:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 1 || vals[0] != &name {
+		t.Fatalf("only WHERE values should be preserved: %v", vals)
+	}
+}
+
+func TestTupleIn(t *testing.T) {
+	s, vals, err := TupleIn([]string{"order_id", "line"},
+		[]driver.Value{1, 1},
+		[]driver.Value{1, 2},
+		[]driver.Value{2, 1},
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "(order_id, line) IN ((?, ?), (?, ?), (?, ?))" {
+		t.Fatalf("wrong tuple IN: %q", s)
+	}
+
+	expected := []driver.Value{1, 1, 1, 2, 2, 1}
+	if len(vals) != len(expected) {
+		t.Fatalf("wrong values: %v", vals)
+	}
+	for i := range expected {
+		if vals[i] != expected[i] {
+			t.Fatalf("values should be flattened in row-major order: %v", vals)
+		}
+	}
+
+	s, vals, err = TupleIn([]string{"order_id", "line"})()
+	if s != "" || vals != nil || err != nil {
+		t.Fatal("no rows should be a no-op")
+	}
+
+	if _, _, err = TupleIn(nil, []driver.Value{})(); !errors.Is(err, ErrNoColumns) {
+		t.Fatalf("expected no columns error, got %v", err)
+	}
+
+	_, _, err = TupleIn([]string{"order_id", "line"}, []driver.Value{1, 1}, []driver.Value{1})()
+	if !errors.Is(err, ErrColumnCountMismatch) {
+		t.Fatalf("expected column count mismatch, got %v", err)
+	}
+}
+
+func TestInSubQuery(t *testing.T) {
+	status, name := "paid", "test"
+	s, vals, err := And(
+		Eq("users.name", &name),
+		InSubQuery("users.id",
+			New(
+				Select(Just("user_id")),
+				From(Just("orders")),
+				Where(Eq("status", &status)),
+			),
+		),
+		NotInSubQuery("users.id", NoOp),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(users.name = ?\nAND users.id IN (\nSELECT\n\tuser_id\nFROM orders\nWHERE\n\tstatus = ?\n\n\n)\n)"
+	if s != expected {
+		t.Fatalf("wrong IN subquery:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 2 || vals[0] != &name || vals[1] != &status {
+		t.Fatalf("subquery values should flow through: %v", vals)
+	}
+}
+
+func TestJoinUsing(t *testing.T) {
+	s, _, err := LeftJoinUsing(Just("orders"), "user_id")()
+	if err != nil || s != "LEFT JOIN orders USING (user_id)" {
+		t.Fatalf("wrong single-column USING: %q %v", s, err)
+	}
+
+	s, _, err = RightJoinUsing(Just("order_lines"), "order_id", "store_id")()
+	if err != nil || s != "RIGHT JOIN order_lines USING (order_id, store_id)" {
+		t.Fatalf("wrong multi-column USING: %q %v", s, err)
+	}
+
+	if _, _, err = JoinUsing(INNER_JOIN, Just("orders"))(); !errors.Is(err, ErrNoColumns) {
+		t.Fatalf("expected no columns error, got %v", err)
+	}
+}
+
+func TestJoinConditions(t *testing.T) {
+	status, store := "paid", 3
+	expected := "INNER JOIN orders ON (orders.user_id = users.id\nAND orders.store_id = users.store_id\nAND orders.status = ?\nAND orders.store = ?\n)"
+
+	s, vals, err := Join(INNER_JOIN, Just("orders"),
+		And(
+			ColumnEq("orders.user_id", "users.id"),
+			ColumnEq("orders.store_id", "users.store_id"),
+			Eq("orders.status", &status),
+			Eq("orders.store", &store),
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected || len(vals) != 2 || vals[0] != &status || vals[1] != &store {
+		t.Fatalf("wrong multi-condition join: %q %v", s, vals)
+	}
+
+	s, vals, err = JoinOn(INNER_JOIN, Just("orders"),
+		ColumnEq("orders.user_id", "users.id"),
+		ColumnEq("orders.store_id", "users.store_id"),
+		Eq("orders.status", &status),
+		Eq("orders.store", &store),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != expected || len(vals) != 2 || vals[0] != &status || vals[1] != &store {
+		t.Fatalf("wrong JoinOn: %q %v", s, vals)
+	}
+}
+
+func TestGroupingSets(t *testing.T) {
+	for expected, op := range map[string]SqldFn{
+		"GROUP BY\nROLLUP(country, city)":                         GroupBy(Rollup("country", "city")),
+		"GROUP BY\nCUBE(country, city)":                           GroupBy(Cube("country", "city")),
+		"GROUP BY\nGROUPING SETS((country, city), (country), ())": GroupBy(GroupingSets([]string{"country", "city"}, []string{"country"}, nil)),
+		"GROUP BY\nstore_id,\n\tROLLUP(country, city)":            GroupBy(Just("store_id"), Rollup("country", "city")),
+	} {
+		s, vals, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 0 {
+			t.Fatalf("wrong grouping:\n%q\n%q", s, expected)
+		}
+	}
+
+	if _, _, err := GroupBy(Rollup())(); !errors.Is(err, ErrNoColumns) {
+		t.Fatalf("expected no columns error, got %v", err)
+	}
+}
+
+func TestLikeEscape(t *testing.T) {
+	pattern := "%" + EscapeLikePattern(`50%_off\`, '\\') + "%"
+	s, vals, err := LikeEscape("name", &pattern, '\\')()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != `name LIKE ? ESCAPE '\'` {
+		t.Fatalf("wrong LIKE ESCAPE: %q", s)
+	}
+	if len(vals) != 1 || *(vals[0].(*string)) != `%50\%\_off\\%` {
+		t.Fatalf("wildcards in user input should be neutralized: %v", vals)
+	}
+
+	var missing *string
+	if _, _, err = LikeEscape("name", missing, '\\')(); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
+
+func TestDistinctFrom(t *testing.T) {
+	managerID := 7
+	s, vals, err := DistinctFrom("manager_id", &managerID)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "manager_id IS DISTINCT FROM ?" || len(vals) != 1 || vals[0] != &managerID {
+		t.Fatalf("wrong IS DISTINCT FROM: %q %v", s, vals)
+	}
+
+	s, vals, err = NotDistinctFrom[int]("manager_id", nil)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "manager_id IS NOT DISTINCT FROM NULL" || len(vals) != 0 {
+		t.Fatalf("nil value should compare with NULL: %q %v", s, vals)
+	}
+
+	s, _, _ = DistinctFrom[int]("manager_id", nil)()
+	if s != "manager_id IS DISTINCT FROM NULL" {
+		t.Fatalf("nil value should compare with NULL: %q", s)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	_, _, err := Eq[string]("name", nil)()
+	if !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+
+	_, _, err = New(Select(Columns("id")), Where(And(Eq[string]("name", nil))))()
+	if !errors.Is(err, ErrNilVal) {
+		t.Fatalf("nested errors should be wrapped, got %v", err)
+	}
+
+	_, _, err = In[string]("pizzas", nil)()
+	if !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+
+	for _, op := range []SqldFn{Select(), And(), Where(), CoalesceOps()} {
+		if _, _, err := op(); !errors.Is(err, ErrNoOps) {
+			t.Fatalf("expected no operators error, got %v", err)
+		}
+	}
+	if _, _, err := Columns()(); !errors.Is(err, ErrNoColumns) {
+		t.Fatalf("expected no columns error, got %v", err)
+	}
+}
+
+func TestGreatestLeast(t *testing.T) {
+	s, vals, err := Greatest(Val(1), Val(2))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "GREATEST(?, ?)" || len(vals) != 2 || vals[0] != 1 || vals[1] != 2 {
+		t.Fatalf("wrong two-argument GREATEST: %q %v", s, vals)
+	}
+
+	s, vals, err = Least(Val("a"), Just("name"), Coalesce(Val("c"), "'z'"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "LEAST(?, name, COALESCE(?, 'z'))" {
+		t.Fatalf("wrong three-argument LEAST: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != "a" || vals[1] != "c" {
+		t.Fatalf("values should follow the arguments order: %v", vals)
+	}
+
+	for _, op := range []SqldFn{Greatest(), Least()} {
+		if _, _, err := op(); !errors.Is(err, ErrNoOps) {
+			t.Fatalf("expected no operators error, got %v", err)
+		}
+	}
+}
+
+func TestLateralJoin(t *testing.T) {
+	status, minTotal := "paid", 10
+	one := uint(1)
+	sub := SubQuery(New(
+		Select(Columns("orders.total")),
+		From(Just("orders")),
+		Where(And(
+			ColumnEq("orders.user_id", "users.id"),
+			Eq("orders.status", &status),
+		)),
+		Limit(&one),
+	), "last_order")
+
+	s, vals, err := LateralJoin(LEFT_JOIN, sub, Eq("last_order.total", &minTotal))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s, "LEFT JOIN LATERAL (\nSELECT") || !strings.HasSuffix(s, ") AS last_order ON last_order.total = ?") {
+		t.Fatalf("wrong LATERAL join: %q", s)
+	}
+	if len(vals) != 3 || vals[0] != &status || vals[1] != one || vals[2] != &minTotal {
+		t.Fatalf("subquery values should come before the condition ones: %v", vals)
+	}
+
+	s, vals, err = CrossJoinLateral(sub)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s, "CROSS JOIN LATERAL (\nSELECT") || !strings.HasSuffix(s, ") AS last_order") {
+		t.Fatalf("wrong CROSS JOIN LATERAL: %q", s)
+	}
+	if len(vals) != 2 {
+		t.Fatalf("wrong values: %v", vals)
+	}
+}
+
+func TestIsPredicates(t *testing.T) {
+	for expected, op := range map[string]SqldFn{
+		"active IS NULL":      Null("active"),
+		"active IS NOT NULL":  NotNull("active"),
+		"active IS TRUE":      IsTrue("active"),
+		"active IS FALSE":     IsFalse("active"),
+		"active IS NOT TRUE":  IsNotTrue("active"),
+		"active IS NOT FALSE": IsNotFalse("active"),
+	} {
+		s, vals, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 0 {
+			t.Fatalf("wrong predicate: %q %v", s, vals)
+		}
+	}
+}
+
+func TestColumnCmp(t *testing.T) {
+	for expected, op := range map[string]SqldFn{
+		"a.x = b.x":  ColumnEq("a.x", "b.x"),
+		"a.x <> b.x": ColumnNeq("a.x", "b.x"),
+		"a.x > b.x":  ColumnGt("a.x", "b.x"),
+		"a.x >= b.x": ColumnGte("a.x", "b.x"),
+		"a.x < b.x":  ColumnLt("a.x", "b.x"),
+		"a.x <= b.x": ColumnLte("a.x", "b.x"),
+		"a.x != b.x": ColumnCmp("a.x", "!=", "b.x"),
+	} {
+		s, vals, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 0 {
+			t.Fatalf("wrong column comparison: %q %v", s, vals)
+		}
+	}
+
+	if _, _, err := ColumnCmp("a.x", "= b.x; DROP TABLE a; --", "b.x")(); !errors.Is(err, ErrInvalidOperator) {
+		t.Fatalf("expected invalid operator error, got %v", err)
+	}
+}
+
+func TestRaw(t *testing.T) {
+	s, vals, err := Raw("price BETWEEN ? AND ? AND label <> '?'", 5, 10)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "price BETWEEN ? AND ? AND label <> '?'" || len(vals) != 2 || vals[0] != 5 || vals[1] != 10 {
+		t.Fatalf("wrong raw fragment: %q %v", s, vals)
+	}
+
+	s, vals, err = Raw("deleted_at IS NULL")()
+	if err != nil || s != "deleted_at IS NULL" || len(vals) != 0 {
+		t.Fatalf("wrong valueless raw fragment: %q %v %v", s, vals, err)
+	}
+
+	if _, _, err := Raw("price BETWEEN ? AND ?", 5)(); !errors.Is(err, ErrPlaceholderMismatch) {
+		t.Fatalf("expected placeholder mismatch, got %v", err)
+	}
+	if _, _, err := Raw("price > 5", 5)(); !errors.Is(err, ErrPlaceholderMismatch) {
+		t.Fatalf("expected placeholder mismatch, got %v", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	for _, tc := range []struct {
+		page, size, offset uint
+	}{
+		{page: 0, size: 20, offset: 0},
+		{page: 1, size: 20, offset: 0},
+		{page: 3, size: 20, offset: 40},
+	} {
+		s, vals, err := Paginate(tc.page, tc.size)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "LIMIT ? OFFSET ?" {
+			t.Fatalf("wrong pagination: %q", s)
+		}
+		if len(vals) != 2 || vals[0] != tc.size || vals[1] != tc.offset {
+			t.Fatalf("wrong pagination values for page %d: %v", tc.page, vals)
+		}
+	}
+
+	s, vals, err := Paginate(3, 0)()
+	if s != "" || vals != nil || err != nil {
+		t.Fatalf("zero size should be a no-op, got %q %v %v", s, vals, err)
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	for _, tc := range []struct {
+		from, to *time.Time
+		expected string
+		vals     []driver.Value
+	}{
+		{from: &from, expected: "created_at >= ?", vals: []driver.Value{from}},
+		{to: &to, expected: "created_at < ?", vals: []driver.Value{to}},
+		{from: &from, to: &to, expected: "created_at >= ? AND created_at < ?", vals: []driver.Value{from, to}},
+		{expected: ""},
+	} {
+		s, vals, err := DateRange("created_at", tc.from, tc.to)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != tc.expected || len(vals) != len(tc.vals) {
+			t.Fatalf("wrong date range: %q %v", s, vals)
+		}
+		for i := range vals {
+			if vals[i] != tc.vals[i] {
+				t.Fatalf("wrong date range values: %v", vals)
+			}
+		}
+	}
+}
+
+func TestCondPrecedence(t *testing.T) {
+	status, role, name := "paid", "admin", "mario"
+	s, vals, err := And(
+		Eq("status", &status),
+		Or(
+			Eq("role", &role),
+			Eq("name", &name),
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "(status = ?\nAND (role = ?\nOR name = ?\n)\n)" {
+		t.Fatalf("nested conditions should be parenthesized: %q", s)
+	}
+	if len(vals) != 3 || vals[0] != &status || vals[2] != &name {
+		t.Fatalf("wrong values: %v", vals)
+	}
+
+	s, _, err = Where(RawCond(AND, Eq("status", &status), Eq("role", &role)))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "WHERE\n\tstatus = ?\nAND role = ?\n" {
+		t.Fatalf("raw conditions should not be parenthesized: %q", s)
+	}
+
+	if s, _, err := RawCond(OR, NoOp)(); s != "" || err != nil {
+		t.Fatalf("empty raw condition should be empty: %q %v", s, err)
+	}
+	if _, _, err := RawCond(OR)(); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no operators error, got %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	minOrders, status := 3, "paid"
+	s, vals, err := Having(Compare(Count(Just("id")), ">=", &minOrders))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "HAVING\n\tCOUNT(id) >= ?\n" || len(vals) != 1 || vals[0] != &minOrders {
+		t.Fatalf("wrong aggregate comparison: %q %v", s, vals)
+	}
+
+	s, vals, err = Compare(ScalarSubQuery(New(
+		Select(Count(AllWildcard())),
+		From(Just("orders")),
+		Where(Eq("status", &status)),
+	)), ">", &minOrders)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(s, ") > ?") || len(vals) != 2 || vals[0] != &status || vals[1] != &minOrders {
+		t.Fatalf("expression values should come first: %q %v", s, vals)
+	}
+
+	s, _, err = HavingCount(">=", &minOrders)()
+	if err != nil || s != "HAVING\n\tCOUNT(*) >= ?\n" {
+		t.Fatalf("wrong HavingCount: %q %v", s, err)
+	}
+	s, _, err = HavingSum("orders.total", "<", &minOrders)()
+	if err != nil || s != "HAVING\n\tSUM(orders.total) < ?\n" {
+		t.Fatalf("wrong HavingSum: %q %v", s, err)
+	}
+
+	if _, _, err := Compare(Just("x"), "LIKE", &minOrders)(); !errors.Is(err, ErrInvalidOperator) {
+		t.Fatalf("expected invalid operator error, got %v", err)
+	}
+	if _, _, err := Compare[int](Just("x"), ">", nil)(); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	s, _, err := As(Count(Just("id")), "total_orders")()
+	if err != nil || s != "COUNT(id) AS total_orders" {
+		t.Fatalf("wrong alias: %q %v", s, err)
+	}
+
+	s, _, err = QuotedAs(Count(Just("id")), `Total "orders"`)()
+	if err != nil || s != `COUNT(id) AS "Total ""orders"""` {
+		t.Fatalf("wrong quoted alias: %q %v", s, err)
+	}
+
+	s, _, err = SubQuery(Values([]driver.Value{1}), "t(id)")()
+	if err != nil || !strings.HasSuffix(s, ") AS t(id)") {
+		t.Fatalf("column aliases should be allowed: %q %v", s, err)
+	}
+
+	for _, alias := range []string{"", "x; DROP TABLE users; --", "total orders", `"x"`, "t(id", "t(id; --)"} {
+		if _, _, err := As(Just("1"), alias)(); !errors.Is(err, ErrInvalidAlias) {
+			t.Fatalf("alias %q should be rejected, got %v", alias, err)
+		}
+		if _, _, err := SubQuery(Just("SELECT 1"), alias)(); !errors.Is(err, ErrInvalidAlias) {
+			t.Fatalf("alias %q should be rejected, got %v", alias, err)
+		}
+	}
+}
+
+func TestDistinctAggregates(t *testing.T) {
+	s, vals, err := CountDistinct(Just("user_id"))()
+	if err != nil || s != "COUNT(DISTINCT user_id)" || len(vals) != 0 {
+		t.Fatalf("wrong COUNT(DISTINCT): %q %v %v", s, vals, err)
+	}
+
+	fallback := 0
+	s, vals, err = SumDistinct(CoalesceOps(Just("amount"), ValPtr(&fallback)))()
+	if err != nil || s != "SUM(DISTINCT COALESCE(amount, ?))" {
+		t.Fatalf("wrong SUM(DISTINCT): %q %v", s, err)
+	}
+	if len(vals) != 1 || vals[0] != 0 {
+		t.Fatalf("inner values should be propagated: %v", vals)
+	}
+
+	s, _, err = Avg(DistinctExpr(Just("price")))()
+	if err != nil || s != "AVG(DISTINCT price)" {
+		t.Fatalf("wrong AVG(DISTINCT): %q %v", s, err)
+	}
+}
+
+func TestLimitOffsetInt(t *testing.T) {
+	negative, zero, positive := -1, 0, 20
+	for _, tc := range []struct {
+		n        *int
+		expected bool
+	}{
+		{n: nil},
+		{n: &negative},
+		{n: &zero, expected: true},
+		{n: &positive, expected: true},
+	} {
+		for keyword, op := range map[string]SqldFn{"LIMIT ?": LimitInt(tc.n), "OFFSET ?": OffsetInt(tc.n)} {
+			s, vals, err := op()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tc.expected {
+				if s != "" || vals != nil {
+					t.Fatalf("nil or negative count should be a no-op, got %q %v", s, vals)
+				}
+				continue
+			}
+			if s != keyword || len(vals) != 1 || vals[0] != *tc.n {
+				t.Fatalf("wrong clause: %q %v", s, vals)
+			}
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	s, vals, err := GroupBy(Just("country"), NoOp, Just("city"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "GROUP BY\ncountry,\n\tcity" || len(vals) != 0 {
+		t.Fatalf("wrong GROUP BY: %q %v", s, vals)
+	}
+
+	precision := 2
+	s, vals, err = GroupBy(Just("country"), Raw("round(price, ?)", precision))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "GROUP BY\ncountry,\n\tround(price, ?)" || len(vals) != 1 || vals[0] != precision {
+		t.Fatalf("values should be propagated: %q %v", s, vals)
+	}
+
+	if s, vals, err := GroupBy(NoOp, Skip())(); s != "" || vals != nil || err != nil {
+		t.Fatalf("all-empty GROUP BY should be dropped, got %q %v %v", s, vals, err)
+	}
+	if _, _, err := GroupBy()(); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no operators error, got %v", err)
+	}
+}
+
+func TestSort(t *testing.T) {
+	for _, param := range []string{"ASC", "DESC"} {
+		s, _, err := OrderBy(Sort(SortingOrder(param), "created_at"))()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "ORDER BY\ncreated_at "+param {
+			t.Fatalf("wrong runtime sorting: %q", s)
+		}
+	}
+
+	if _, _, err := Sort(SortingOrder("DESC; DROP TABLE users"), "created_at")(); !errors.Is(err, ErrInvalidSortingOrder) {
+		t.Fatalf("expected invalid sorting order error, got %v", err)
+	}
+	if _, err := SafeSort([]string{"created_at"}, "created_at", "desc"); !errors.Is(err, ErrInvalidSortingOrder) {
+		t.Fatalf("expected invalid sorting order error, got %v", err)
+	}
+}
+
+func TestSortNulls(t *testing.T) {
+	for expected, op := range map[string]SqldFn{
+		"published_at ASC NULLS FIRST":  AscNullsFirst("published_at"),
+		"published_at ASC NULLS LAST":   AscNullsLast("published_at"),
+		"published_at DESC NULLS FIRST": DescNullsFirst("published_at"),
+		"published_at DESC NULLS LAST":  DescNullsLast("published_at"),
+	} {
+		s, _, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected {
+			t.Fatalf("wrong NULLs sorting: %q", s)
+		}
+	}
+
+	s, _, err := OrderBy(DescNullsLast("published_at"), Asc("id"))()
+	if err != nil || s != "ORDER BY\npublished_at DESC NULLS LAST,\n\tid ASC" {
+		t.Fatalf("wrong ORDER BY: %q %v", s, err)
+	}
+
+	if _, _, err := SortNulls(ASC, "NULLS SOMEWHERE", "published_at")(); !errors.Is(err, ErrInvalidSortingOrder) {
+		t.Fatalf("expected invalid sorting order error, got %v", err)
+	}
+}
+
+func TestWhereOrTrue(t *testing.T) {
+	var name *string
+	s, vals, err := WhereOrTrue(IfNotNil(name, Eq("name", name)), And(NoOp))()
+	if err != nil || s != "WHERE\n\tTRUE\n" || len(vals) != 0 {
+		t.Fatalf("empty filters should render WHERE TRUE: %q %v %v", s, vals, err)
+	}
+
+	pizza := "margherita"
+	name = &pizza
+	s, vals, err = WhereOrTrue(IfNotNil(name, Eq("name", name)))()
+	if err != nil || s != "WHERE\n\tname = ?\n" || len(vals) != 1 || vals[0] != name {
+		t.Fatalf("filters should render as in Where: %q %v %v", s, vals, err)
+	}
+
+	if _, _, err = WhereOrTrue(Eq[string]("name", nil))(); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
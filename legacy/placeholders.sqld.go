@@ -0,0 +1,114 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// placeholderIndexes returns the byte offsets of all ? placeholders in the query,
+// skipping single-quoted strings, double-quoted identifiers and dollar-quoted blocks
+func placeholderIndexes(query string) []int {
+	return codeIndexes(query, func(i int) bool {
+		return query[i] == '?'
+	})
+}
+
+// codeIndexes returns the byte offsets matched by the callback in the query,
+// skipping single-quoted strings, double-quoted identifiers and dollar-quoted blocks
+func codeIndexes(query string, match func(i int) bool) []int {
+	indexes := make([]int, 0)
+
+	for i := 0; i < len(query); i++ {
+		if match(i) {
+			indexes = append(indexes, i)
+			continue
+		}
+
+		switch query[i] {
+		case '\'', '"':
+			// doubled quotes are escapes, so they just close and reopen the literal
+			end := strings.IndexByte(query[i+1:], query[i])
+			if end == -1 {
+				return indexes
+			}
+			i += end + 1
+		case '$':
+			tag, ok := dollarTag(query[i:])
+			if !ok {
+				continue
+			}
+
+			end := strings.Index(query[i+len(tag):], tag)
+			if end == -1 {
+				return indexes
+			}
+			i += len(tag) + end + len(tag) - 1
+		}
+	}
+
+	return indexes
+}
+
+// dollarTag returns the opening `$tag$` of a dollar-quoted block, if s starts with one
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+// replacePlaceholders replaces the first n ? placeholders (all of them, if n is negative)
+// with the result of the callback, called with the placeholder index
+func replacePlaceholders(query string, n int, replace func(i int) string) string {
+	var sb strings.Builder
+
+	last := 0
+	for i, index := range placeholderIndexes(query) {
+		if n >= 0 && i >= n {
+			break
+		}
+
+		sb.WriteString(query[last:index])
+		sb.WriteString(replace(i))
+		last = index + 1
+	}
+	sb.WriteString(query[last:])
+
+	return sb.String()
+}
+
+// CountPlaceholders returns the number of ? placeholders in the query,
+// ignoring the ones inside string literals, quoted identifiers and dollar-quoted blocks
+func CountPlaceholders(query string) int {
+	return len(placeholderIndexes(query))
+}
+
+// AssertBound runs the operator and checks that every placeholder has a corresponding value.
+// Returns `ErrUnboundPlaceholder` if there are more placeholders than values,
+// and `ErrExtraValues` if there are less.
+func AssertBound(op SqldFn) (string, []driver.Value, error) {
+	query, vals, err := op()
+	if err != nil {
+		return "", nil, err
+	}
+
+	count := CountPlaceholders(query)
+	if count > len(vals) {
+		return "", nil, fmt.Errorf("assert bound (%d placeholders, %d values): %w", count, len(vals), ErrUnboundPlaceholder)
+	}
+	if count < len(vals) {
+		return "", nil, fmt.Errorf("assert bound (%d placeholders, %d values): %w", count, len(vals), ErrExtraValues)
+	}
+
+	return query, vals, nil
+}
@@ -0,0 +1,35 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestCountPlaceholders(t *testing.T) {
+	query := `SELECT ?, '?', 'it''s ?', "wh?t", $$ ? $$, $fn$ ? $fn$, $1 FROM t WHERE a = ?`
+	if n := CountPlaceholders(query); n != 2 {
+		t.Fatalf("expected 2 placeholders, got %d", n)
+	}
+}
+
+func TestAssertBound(t *testing.T) {
+	name := "test"
+
+	s, vals, err := AssertBound(Eq("name", &name))
+	if err != nil || s != "name = ?" || len(vals) != 1 {
+		t.Fatalf("balanced query should pass: %q %v %v", s, vals, err)
+	}
+
+	_, _, err = AssertBound(Just("name = ? AND surname = ?"))
+	if !errors.Is(err, ErrUnboundPlaceholder) {
+		t.Fatalf("expected unbound placeholder error, got %v", err)
+	}
+
+	_, _, err = AssertBound(func() (string, []driver.Value, error) {
+		return "name = '?'", []driver.Value{name}, nil
+	})
+	if !errors.Is(err, ErrExtraValues) {
+		t.Fatalf("expected extra values error, got %v", err)
+	}
+}
@@ -2,20 +2,36 @@ package sqld_legacy
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// PgPrepare swaps all ? placeholders with postgres ones ($1, $2...)
+var ErrInvalidIntervalUnit = errors.New("invalid interval unit")
+var ErrInvalidFraction = errors.New("fraction is not between 0 and 1")
+
+var intervalUnits = []string{"years", "months", "weeks", "days", "hours", "mins", "secs"}
+
+// PgPrepare swaps all ? placeholders with postgres ones ($1, $2...).
+// Question marks inside string literals, quoted identifiers and dollar-quoted blocks are left untouched.
 func PgPrepare(query string, args []driver.Value) string {
-	for i := 1; i <= len(args); i++ {
-		query = strings.Replace(query, "?", fmt.Sprintf("$%d", i), 1)
-	}
+	return PgPrepareFrom(query, args, 1)
+}
 
-	return query
+// PgPrepareFrom is like `PgPrepare()`, numbering the placeholders from startIndex.
+// Use it to stitch the query after a fragment that already uses the first placeholders.
+//
+//	sqld.PgPrepareFrom(query, args, 4) // $4, $5...
+func PgPrepareFrom(query string, args []driver.Value, startIndex int) string {
+	return replacePlaceholders(query, len(args), func(i int) string {
+		return Postgres.placeholder(startIndex + i)
+	})
 }
 
-// PgPrepareOp applies PgPrepare() to the resulting query in the operator.
+// PgPrepareOp applies PgPrepareShared() to the resulting query in the operator.
 // Use this as the last operator!
 func PgPrepareOp(op SqldFn) SqldFn {
 	return func() (string, []driver.Value, error) {
@@ -24,6 +40,535 @@ func PgPrepareOp(op SqldFn) SqldFn {
 			return "", nil, err
 		}
 
-		return PgPrepare(query, args), args, nil
+		query, args = PgPrepareShared(query, args)
+		return query, args, nil
+	}
+}
+
+// BuildPg runs the operator and prepares the resulting query for Postgres,
+// returning the values as []any, ready for `database/sql` or `pgx`.
+//
+//	query, args, err := sqld.BuildPg(sqld.New(...))
+//	rows, err := db.Query(query, args...)
+func BuildPg(op SqldFn) (string, []any, error) {
+	query, vals, err := PgPrepareOp(op)()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, Args(vals), nil
+}
+
+// MustBuildPg is like `BuildPg()`, but panics on error. Use it for static queries
+func MustBuildPg(op SqldFn) (string, []any) {
+	query, args, err := BuildPg(op)
+	if err != nil {
+		panic(err)
+	}
+
+	return query, args
+}
+
+// UnnestColumn builds a callback that expands an array column into a joinable set of rows.
+//
+//	sqld.Join(sqld.INNER_JOIN, sqld.UnnestColumn("posts.tags", "tag"), sqld.Just("TRUE"))
+func UnnestColumn(column string, alias string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return "unnest(" + column + ") AS " + alias, nil, nil
+	}
+}
+
+// Interval builds a callback that returns a parameterized interval of the given unit,
+// using `make_interval` to avoid concatenating interval literals.
+// The unit must be one of the `make_interval` arguments (years, months, weeks, days, hours, mins, secs).
+//
+//	sqld.Interval(filters.Days, "days") // make_interval(days => ?)
+func Interval(amount *int, unit string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if !slices.Contains(intervalUnits, unit) {
+			return "", nil, fmt.Errorf("interval (%s): %w", unit, ErrInvalidIntervalUnit)
+		}
+
+		if amount == nil {
+			return "", nil, nil
+		}
+
+		return "make_interval(" + unit + " => ?)", []driver.Value{*amount}, nil
+	}
+}
+
+// IntervalDays is a shortcut for `Interval()` with days unit
+func IntervalDays(n *int) SqldFn {
+	return Interval(n, "days")
+}
+
+func percentile(name string, fraction float64, orderBy SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if fraction < 0 || fraction > 1 {
+			return "", nil, fmt.Errorf("%s (%v): %w", name, fraction, ErrInvalidFraction)
+		}
+
+		s, vals, err := orderBy()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		return fmt.Sprintf("%s(%s) WITHIN GROUP (ORDER BY %s)", name, strconv.FormatFloat(fraction, 'f', -1, 64), s), vals, nil
+	}
+}
+
+// PercentileCont builds a callback that returns the continuous percentile of the ordered expression,
+// interpolating between adjacent values if needed.
+//
+//	sqld.PercentileCont(0.95, sqld.Just("latency")) // percentile_cont(0.95) WITHIN GROUP (ORDER BY latency)
+func PercentileCont(fraction float64, orderBy SqldFn) SqldFn {
+	return percentile("percentile_cont", fraction, orderBy)
+}
+
+// PercentileDisc builds a callback that returns the first value of the ordered expression
+// whose position is equal or greater than the given fraction
+func PercentileDisc(fraction float64, orderBy SqldFn) SqldFn {
+	return percentile("percentile_disc", fraction, orderBy)
+}
+
+// RangeOverlaps builds a callback that checks if the range column overlaps the given time range.
+// A nil bound leaves the range unbounded on that side; if both bounds are nil, the filter is skipped.
+//
+//	sqld.RangeOverlaps("booking.during", filters.From, filters.To) // booking.during && tstzrange(?, ?)
+func RangeOverlaps(column string, low, high *time.Time) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if low == nil && high == nil {
+			return "", nil, nil
+		}
+
+		vals := make([]driver.Value, 0, 2)
+		for _, bound := range []*time.Time{low, high} {
+			if bound == nil {
+				vals = append(vals, nil)
+				continue
+			}
+
+			vals = append(vals, *bound)
+		}
+
+		return column + " && tstzrange(?, ?)", vals, nil
+	}
+}
+
+// CountDistinctOn builds a callback that counts the distinct groups of the provided columns,
+// wrapping a `SELECT DISTINCT ON (columns)` over the provided FROM (and filters) in a `SELECT COUNT(*)`.
+//
+//	sqld.CountDistinctOn([]string{"customer_id"},
+//		sqld.New(
+//			sqld.From(sqld.Just("orders")),
+//			sqld.Where(sqld.Eq("status", &status)),
+//		),
+//	)
+func CountDistinctOn(columns []string, from SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(columns) == 0 {
+			return "", nil, fmt.Errorf("count distinct on: %w", ErrNoColumns)
+		}
+
+		s, vals, err := from()
+		if err != nil {
+			return "", nil, fmt.Errorf("count distinct on: %w", err)
+		}
+
+		return fmt.Sprintf("SELECT COUNT(*)\nFROM (\nSELECT DISTINCT ON (%s) 1\n%s\n) AS _distinct_on", strings.Join(columns, ", "), s), vals, nil
+	}
+}
+
+// OnConflict builds a callback that returns an ON CONFLICT clause on the target columns,
+// with the provided action (`DoNothing()` or `DoUpdateSet()`).
+// If no targets are provided, the clause applies to any constraint violation (only valid with `DoNothing()`).
+//
+//	sqld.New(
+//		sqld.Insert(sqld.Just("pizzas"), []string{"id", "name"}, []driver.Value{id, name}),
+//		sqld.OnConflict([]string{"id"},
+//			sqld.DoUpdateSet(sqld.SetExcluded("name")),
+//		),
+//	)
+func OnConflict(targets []string, action SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := action()
+		if err != nil {
+			return "", nil, fmt.Errorf("on conflict: %w", err)
+		}
+
+		if len(targets) == 0 {
+			return "ON CONFLICT " + s, vals, nil
+		}
+
+		return "ON CONFLICT (" + strings.Join(targets, ", ") + ") " + s, vals, nil
+	}
+}
+
+// DoNothing builds a callback that returns the DO NOTHING action of an ON CONFLICT clause.
+//
+// Rows skipped because of a conflict are not returned by a RETURNING statement:
+// an insert of a single conflicting row returns no rows at all, so be ready to handle `sql.ErrNoRows`.
+func DoNothing() SqldFn {
+	return Just("DO NOTHING")
+}
+
+// OnConflictDoNothing is a shortcut for `OnConflict()` with `DoNothing()` action.
+// If no targets are provided, any constraint violation is ignored.
+//
+//	sqld.New(
+//		sqld.Insert(sqld.Just("pizzas"), []string{"name"}, []driver.Value{name}),
+//		sqld.OnConflictDoNothing(),
+//		sqld.Returning(sqld.Columns("id")), // no rows if the pizza already exists
+//	)
+func OnConflictDoNothing(targets ...string) SqldFn {
+	return OnConflict(targets, DoNothing())
+}
+
+// DoUpdateSet builds a callback that returns the DO UPDATE action of an ON CONFLICT clause,
+// with a SET list made of the non-empty assignments.
+// Returns `ErrNoOps` if all the assignments are empty.
+func DoUpdateSet(sets ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := setList(sets...)
+		if err != nil {
+			return "", nil, fmt.Errorf("do update: %w", err)
+		}
+
+		return "DO UPDATE SET " + s, vals, nil
+	}
+}
+
+// SetExcluded builds a callback that assigns to a column the value proposed for insertion,
+// to be used in `DoUpdateSet()`
+func SetExcluded(column string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return column + " = EXCLUDED." + column, nil, nil
+	}
+}
+
+// PgCast builds a callback that converts the expression to the provided SQL type,
+// using the postgres `::` syntax
+//
+//	sqld.PgCast(sqld.Just("created_at"), "date") // created_at::date
+func PgCast(op SqldFn, sqlType string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("cast: %w", err)
+		}
+
+		return s + "::" + sqlType, vals, nil
+	}
+}
+
+// TypedEq is like `Eq()`, casting the placeholder to the provided postgres type,
+// for the parameters whose type can't be inferred. The cast survives `PgPrepare()`.
+//
+//	sqld.TypedEq("id", "uuid", filters.ID) // id = ?::uuid
+func TypedEq[T driver.Value](columnExpr string, pgType string, val *T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if val == nil {
+			return "", nil, fmt.Errorf("typed eq (%s): %w", columnExpr, ErrNilVal)
+		}
+
+		return columnExpr + " = ?::" + pgType, []driver.Value{val}, nil
+	}
+}
+
+// ArraySubquery builds a callback that collects the single column returned by the subquery into an array.
+//
+//	sqld.As(
+//		sqld.ArraySubquery(
+//			sqld.New(
+//				sqld.Select(sqld.Just("tag")),
+//				sqld.From(sqld.Just("post_tags")),
+//				sqld.Where(sqld.ColumnEq("post_tags.post_id", "posts.id")),
+//			),
+//		),
+//		"tags",
+//	)
+func ArraySubquery(sub SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := sub()
+		if err != nil {
+			return "", nil, fmt.Errorf("array: %w", err)
+		}
+
+		return "ARRAY(\n" + s + "\n)", vals, nil
+	}
+}
+
+func jsonGet(operator string, columnExpr string, key string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		return columnExpr + " " + operator + " ?", []driver.Value{key}, nil
+	}
+}
+
+// JSONGet builds a callback that extracts a JSON field (as JSON) from a JSON/JSONB column, binding the key.
+//
+//	sqld.JSONGet("data", "address") // data -> ?
+func JSONGet(columnExpr string, key string) SqldFn {
+	return jsonGet("->", columnExpr, key)
+}
+
+// JSONGetText builds a callback that extracts a JSON field (as text) from a JSON/JSONB column, binding the key.
+//
+//	sqld.Select(sqld.As(sqld.JSONGetText("data", "city"), "city")) // data ->> ? AS city
+func JSONGetText(columnExpr string, key string) SqldFn {
+	return jsonGet("->>", columnExpr, key)
+}
+
+func jsonPath(operator string, columnExpr string, path []string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(path) == 0 {
+			return "", nil, fmt.Errorf("json path (%s): %w", columnExpr, ErrEmptySlice)
+		}
+
+		return columnExpr + " " + operator + " ARRAY[" + placeholders(len(path)) + "]::text[]", mapSlice(path), nil
+	}
+}
+
+// JSONPath builds a callback that extracts a nested JSON field (as JSON) from a JSON/JSONB column,
+// binding every path element.
+//
+//	sqld.JSONPath("data", "address", "city") // data #> ARRAY[?, ?]::text[]
+func JSONPath(columnExpr string, path ...string) SqldFn {
+	return jsonPath("#>", columnExpr, path)
+}
+
+// JSONPathText builds a callback that extracts a nested JSON field (as text) from a JSON/JSONB column,
+// binding every path element.
+func JSONPathText(columnExpr string, path ...string) SqldFn {
+	return jsonPath("#>>", columnExpr, path)
+}
+
+func arrayCmp[T driver.Value](operator string, columnExpr string, vals []T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(vals) == 0 {
+			return "", nil, nil
+		}
+
+		return columnExpr + " " + operator + " ARRAY[" + placeholders(len(vals)) + "]", mapSlice(vals), nil
+	}
+}
+
+// ArrayContains builds a callback that checks if an array column contains all the provided values.
+// Like `In()`, an empty slice is a no-op.
+//
+//	sqld.ArrayContains("post.tags", filters.Tags) // post.tags @> ARRAY[?, ?]
+func ArrayContains[T driver.Value](columnExpr string, vals []T) SqldFn {
+	return arrayCmp("@>", columnExpr, vals)
+}
+
+// ArrayContainedBy builds a callback that checks if all the elements of an array column are in the provided values.
+// Like `In()`, an empty slice is a no-op.
+func ArrayContainedBy[T driver.Value](columnExpr string, vals []T) SqldFn {
+	return arrayCmp("<@", columnExpr, vals)
+}
+
+// ArrayOverlaps builds a callback that checks if an array column has any element in common with the provided values.
+// Like `In()`, an empty slice is a no-op.
+func ArrayOverlaps[T driver.Value](columnExpr string, vals []T) SqldFn {
+	return arrayCmp("&&", columnExpr, vals)
+}
+
+func arrayQuantifier[T driver.Value](comparison string, columnExpr string, vals []T) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if len(vals) == 0 {
+			return "", nil, nil
+		}
+
+		return columnExpr + " " + comparison + "(?)", []driver.Value{vals}, nil
+	}
+}
+
+// EqAny builds a callback that checks if a column value is contained in the provided slice,
+// bound as a single array parameter: unlike `In()`, it doesn't hit the parameters limit with huge slices.
+// The driver must support array parameters (e.g. pgx, or lib/pq with `pq.Array()`).
+// Like `In()`, an empty slice is a no-op.
+//
+//	sqld.EqAny("pizzas", filters.Pizzas) // pizzas = ANY(?)
+func EqAny[T driver.Value](columnExpr string, vals []T) SqldFn {
+	return arrayQuantifier("= ANY", columnExpr, vals)
+}
+
+// NeqAll is like `EqAny()`, checking that the column value is not contained in the provided slice.
+//
+//	sqld.NeqAll("pizzas", filters.Pizzas) // pizzas <> ALL(?)
+func NeqAll[T driver.Value](columnExpr string, vals []T) SqldFn {
+	return arrayQuantifier("<> ALL", columnExpr, vals)
+}
+
+// Filter builds a callback that restricts the rows considered by the aggregate to the ones matching the condition,
+// with the aggregate values before the condition ones. If the condition is empty, the aggregate is returned as is.
+//
+//	sqld.Filter(sqld.Count(sqld.AllWildcard()), sqld.Eq("status", &status)) // COUNT(*) FILTER (WHERE status = ?)
+func Filter(agg SqldFn, cond SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := agg()
+		if err != nil {
+			return "", nil, fmt.Errorf("filter: %w", err)
+		}
+
+		c, condVals, err := cond()
+		if err != nil {
+			return "", nil, fmt.Errorf("filter: %w", err)
+		}
+
+		if c == "" {
+			return s, vals, nil
+		}
+
+		return s + " FILTER (WHERE " + c + ")", append(slices.Clip(vals), condVals...), nil
+	}
+}
+
+// StringAgg builds a callback that returns a STRING_AGG function concatenating the expression values,
+// binding the separator as a value. The sort operators (e.g. `Asc()`) are optional and order the values
+// inside the aggregate.
+//
+//	sqld.StringAgg(sqld.Just("name"), ", ", sqld.Asc("name")) // STRING_AGG(name, ? ORDER BY name ASC)
+func StringAgg(expr SqldFn, sep string, orderBy ...SqldFn) SqldFn {
+	return orderedAggregate("STRING_AGG", expr, []driver.Value{sep}, orderBy)
+}
+
+// ArrayAgg builds a callback that returns an ARRAY_AGG function collecting the expression values into an array.
+// The sort operators (e.g. `Asc()`) are optional and order the values inside the aggregate.
+//
+//	sqld.ArrayAgg(sqld.Just("id"), sqld.Desc("created_at")) // ARRAY_AGG(id ORDER BY created_at DESC)
+func ArrayAgg(expr SqldFn, orderBy ...SqldFn) SqldFn {
+	return orderedAggregate("ARRAY_AGG", expr, nil, orderBy)
+}
+
+// orderedAggregate renders an aggregate with a placeholder for each extra argument,
+// followed by the optional ORDER BY of its values
+func orderedAggregate(name string, expr SqldFn, args []driver.Value, orderBy []SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := expr()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(name), err)
+		}
+
+		if len(args) != 0 {
+			s += ", " + placeholders(len(args))
+			vals = append(slices.Clip(vals), args...)
+		}
+
+		parts, size, orderVals, errs := runOps(orderBy)
+		if errs != nil {
+			return "", nil, fmt.Errorf("%s: %w", strings.ToLower(name), errs)
+		}
+
+		if len(parts) != 0 {
+			s = joinParts(s+" ORDER BY ", parts, size, ", ", "")
+			vals = append(slices.Clip(vals), orderVals...)
+		}
+
+		return name + "(" + s + ")", vals, nil
+	}
+}
+
+// JSONField builds a callback that returns a key-value pair for `JSONBuildObject()`.
+// The key is rendered as a string literal, escaping single quotes.
+func JSONField(key string, value SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := value()
+		if err != nil {
+			return "", nil, fmt.Errorf("json field (%s): %w", key, err)
+		}
+
+		return "'" + strings.ReplaceAll(key, "'", "''") + "', " + s, vals, nil
+	}
+}
+
+// JSONBuildObject builds a callback that returns a JSONB object made of the provided `JSONField()`s.
+// Empty fields are skipped.
+//
+//	sqld.JSONBuildObject(
+//		sqld.JSONField("id", sqld.Just("child.id")),
+//		sqld.JSONField("name", sqld.Just("child.name")),
+//	)
+func JSONBuildObject(fields ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		pairs, vals := make([]string, 0, len(fields)), make([]driver.Value, 0)
+		for _, field := range fields {
+			s, fieldVals, err := field()
+			if err != nil {
+				return "", nil, fmt.Errorf("jsonb_build_object: %w", err)
+			}
+
+			if s == "" {
+				continue
+			}
+
+			pairs = append(pairs, s)
+
+			if len(fieldVals) != 0 {
+				vals = append(vals, fieldVals...)
+			}
+		}
+
+		return "jsonb_build_object(" + strings.Join(pairs, ", ") + ")", vals, nil
+	}
+}
+
+// JSONBAgg builds a callback that aggregates the expression into a JSONB array,
+// optionally ordering the elements.
+//
+//	sqld.JSONBAgg(
+//		sqld.JSONBuildObject(
+//			sqld.JSONField("id", sqld.Just("child.id")),
+//			sqld.JSONField("name", sqld.Just("child.name")),
+//		),
+//		sqld.Asc("child.name"),
+//	)
+func JSONBAgg(arg SqldFn, order ...SqldFn) SqldFn {
+	return orderedAggregate("JSONB_AGG", arg, nil, order)
+}
+
+// ScalarInArray builds a callback that checks if the provided value is contained in the array column.
+// If the value is nil, the filter is skipped.
+//
+//	sqld.ScalarInArray(filters.Tag, "posts.tags") // ? = ANY(posts.tags)
+func ScalarInArray[T driver.Value](val *T, arrayColumn string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if val == nil {
+			return "", nil, nil
+		}
+
+		return "? = ANY(" + arrayColumn + ")", []driver.Value{val}, nil
+	}
+}
+
+func regex(operator string, columnExpr string, pattern *string) SqldFn {
+	return func() (string, []driver.Value, error) {
+		if pattern == nil {
+			return "", nil, fmt.Errorf("regex (%s): %w", columnExpr, ErrNilVal)
+		}
+
+		return columnExpr + " " + operator + " ?", []driver.Value{pattern}, nil
 	}
 }
+
+// Regex builds a callback that checks if a column text matches the provided POSIX regex.
+//
+//	sqld.Regex("name", filters.NamePattern) // name ~ ?
+func Regex(columnExpr string, pattern *string) SqldFn {
+	return regex("~", columnExpr, pattern)
+}
+
+// IRegex builds a callback that checks if a column text matches the provided POSIX regex, ignoring the casing
+func IRegex(columnExpr string, pattern *string) SqldFn {
+	return regex("~*", columnExpr, pattern)
+}
+
+// NotRegex builds a callback that checks if a column text doesn't match the provided POSIX regex
+func NotRegex(columnExpr string, pattern *string) SqldFn {
+	return regex("!~", columnExpr, pattern)
+}
+
+// NotIRegex builds a callback that checks if a column text doesn't match the provided POSIX regex,
+// ignoring the casing
+func NotIRegex(columnExpr string, pattern *string) SqldFn {
+	return regex("!~*", columnExpr, pattern)
+}
@@ -2,7 +2,9 @@ package sqld_legacy
 
 import (
 	"database/sql/driver"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestPgPrepare(t *testing.T) {
@@ -12,3 +14,515 @@ func TestPgPrepare(t *testing.T) {
 		t.Fatal("Prepare failed")
 	}
 }
+
+func TestPgPrepareFrom(t *testing.T) {
+	args := []driver.Value{0, 0, 0}
+	if s := PgPrepareFrom("a = ? AND b = ? AND c = ?", args, 4); s != "a = $4 AND b = $5 AND c = $6" {
+		t.Fatalf("numbering should start from the offset: %s", s)
+	}
+	if PgPrepareFrom("a = ?", args[:1], 1) != PgPrepare("a = ?", args[:1]) {
+		t.Fatal("PgPrepare should number from 1")
+	}
+}
+
+func TestPgPrepareLiterals(t *testing.T) {
+	args := []driver.Value{0, 0, 0}
+	for query, expected := range map[string]string{
+		"WHERE note = 'why?' AND id = ?":             "WHERE note = 'why?' AND id = $1",
+		"WHERE note = 'it''s ?' AND id IN (?,?)":     "WHERE note = 'it''s ?' AND id IN ($1,$2)",
+		`SELECT "what?" FROM t WHERE id = ?`:         `SELECT "what?" FROM t WHERE id = $1`,
+		"SELECT $$ ? $$, $fn$ '?' $fn$, ? ??":        "SELECT $$ ? $$, $fn$ '?' $fn$, $1 $2$3",
+		"SELECT ? FROM t WHERE note = 'unterminated": "SELECT $1 FROM t WHERE note = 'unterminated",
+	} {
+		if prepared := PgPrepare(query, args); prepared != expected {
+			t.Fatalf("wrong prepared query:\n%q\n%q", prepared, expected)
+		}
+	}
+}
+
+func TestBuildPg(t *testing.T) {
+	name, price := "margherita", 5
+	query, args, err := BuildPg(New(
+		Select(Columns("id")),
+		From(Just("pizzas")),
+		Where(And(
+			Eq("name", &name),
+			Eq("price", &price),
+			Just("label <> '?'"),
+		)),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "SELECT\n\tid\nFROM pizzas\nWHERE\n\t(name = $1\nAND price = $2\nAND label <> '?'\n)\n\n" {
+		t.Fatalf("wrong prepared query: %q", query)
+	}
+	if len(args) != 2 || args[0] != &name || args[1] != &price {
+		t.Fatalf("wrong args: %v", args)
+	}
+
+	if _, _, err := BuildPg(Where(Eq[string]("name", nil))); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
+
+func TestMustBuildPg(t *testing.T) {
+	query, args := MustBuildPg(New(Select(Columns("id")), From(Just("pizzas"))))
+	if query != "SELECT\n\tid\nFROM pizzas\n" || len(args) != 0 {
+		t.Fatalf("wrong static query: %q %v", query, args)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	MustBuildPg(Select())
+}
+
+func TestUnnestColumn(t *testing.T) {
+	s, vals, err := Join(INNER_JOIN, UnnestColumn("posts.tags", "tag"), Just("TRUE"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INNER JOIN unnest(posts.tags) AS tag ON TRUE" || len(vals) != 0 {
+		t.Fatalf("wrong unnest join: %s %v", s, vals)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	days := 7
+	s, vals, err := IntervalDays(&days)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "make_interval(days => ?)" || len(vals) != 1 || vals[0] != 7 {
+		t.Fatalf("wrong interval: %s %v", s, vals)
+	}
+
+	s, vals, err = IntervalDays(nil)()
+	if s != "" || vals != nil || err != nil {
+		t.Fatal("nil amount should be a no-op")
+	}
+
+	if _, _, err = Interval(&days, "fortnights")(); !errors.Is(err, ErrInvalidIntervalUnit) {
+		t.Fatalf("expected invalid unit error, got %v", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	s, _, err := PercentileCont(0.95, Just("latency"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "percentile_cont(0.95) WITHIN GROUP (ORDER BY latency)" {
+		t.Fatalf("wrong percentile_cont: %q", s)
+	}
+
+	s, _, err = PercentileDisc(0.5, Desc("latency"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "percentile_disc(0.5) WITHIN GROUP (ORDER BY latency DESC)" {
+		t.Fatalf("wrong percentile_disc: %q", s)
+	}
+
+	if _, _, err = PercentileCont(1.5, Just("latency"))(); !errors.Is(err, ErrInvalidFraction) {
+		t.Fatalf("expected invalid fraction error, got %v", err)
+	}
+}
+
+func TestRangeOverlaps(t *testing.T) {
+	low := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s, vals, err := RangeOverlaps("booking.during", &low, nil)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "booking.during && tstzrange(?, ?)" || len(vals) != 2 || vals[0] != low || vals[1] != nil {
+		t.Fatalf("wrong range overlap: %q %v", s, vals)
+	}
+	if PgPrepare(s, vals) != "booking.during && tstzrange($1, $2)" {
+		t.Fatalf("range syntax mangled: %q", PgPrepare(s, vals))
+	}
+
+	s, vals, err = RangeOverlaps("booking.during", nil, nil)()
+	if s != "" || vals != nil || err != nil {
+		t.Fatal("nil bounds should be a no-op")
+	}
+}
+
+func TestCountDistinctOn(t *testing.T) {
+	status := "paid"
+	s, vals, err := CountDistinctOn([]string{"customer_id", "store_id"},
+		New(
+			From(Just("orders")),
+			Where(Eq("status", &status)),
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT COUNT(*)\nFROM (\nSELECT DISTINCT ON (customer_id, store_id) 1\nFROM orders\nWHERE\n\tstatus = ?\n\n\n) AS _distinct_on"
+	if s != expected {
+		t.Fatalf("wrong DISTINCT ON count:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 1 || vals[0] != &status {
+		t.Fatalf("inner values should flow through: %v", vals)
+	}
+}
+
+func TestOnConflict(t *testing.T) {
+	insert := Insert(Just("pizzas"), []string{"id", "name", "price"}, []driver.Value{1, "margherita", 5})
+
+	s, vals, err := New(insert, OnConflict([]string{"id"}, DoNothing()))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "INSERT INTO pizzas (id, name, price)\nVALUES\n\t(?, ?, ?)\nON CONFLICT (id) DO NOTHING\n" || len(vals) != 3 {
+		t.Fatalf("wrong DO NOTHING: %q %v", s, vals)
+	}
+
+	price := 6
+	var note *string
+	s, vals, err = New(
+		insert,
+		OnConflict([]string{"id"},
+			DoUpdateSet(
+				SetExcluded("name"),
+				Set("price", &price),
+				Set("note", note),
+			),
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "INSERT INTO pizzas (id, name, price)\nVALUES\n\t(?, ?, ?)\nON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, price = ?\n" {
+		t.Fatalf("wrong DO UPDATE: %q", s)
+	}
+	if len(vals) != 4 || vals[3] != &price {
+		t.Fatalf("update set values should follow the inserted ones: %v", vals)
+	}
+
+	if _, _, err = DoUpdateSet(Set("note", note))(); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no ops error, got %v", err)
+	}
+}
+
+func TestCast(t *testing.T) {
+	s, vals, err := Select(
+		As(Cast(Just("created_at"), "date"), "day"),
+		PgCast(Just("created_at"), "date"),
+		Cast(Val("42"), "int"),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "SELECT\n\tCAST(created_at AS date) AS day,\n\tcreated_at::date,\n\tCAST(? AS int)" {
+		t.Fatalf("wrong casts: %q", s)
+	}
+	if len(vals) != 1 || vals[0] != "42" {
+		t.Fatalf("inner values should flow through: %v", vals)
+	}
+
+	var id *int
+	if _, _, err = PgCast(Eq("id", id), "text")(); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("inner error should be propagated, got %v", err)
+	}
+}
+
+func TestArraySubquery(t *testing.T) {
+	active := true
+	s, vals, err := Select(
+		Just("parent.id"),
+		As(
+			ArraySubquery(
+				New(
+					Select(Just("child.name")),
+					From(Just("child")),
+					Where(And(ColumnEq("child.parent_id", "parent.id"), Eq("child.active", &active))),
+				),
+			),
+			"children",
+		),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT\n\tparent.id,\n\tARRAY(\nSELECT\n\tchild.name\nFROM child\nWHERE\n\t(child.parent_id = parent.id\nAND child.active = ?\n)\n\n\n) AS children"
+	if s != expected {
+		t.Fatalf("wrong ARRAY subquery:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 1 || vals[0] != &active {
+		t.Fatalf("subquery values should flow through: %v", vals)
+	}
+}
+
+func TestOnConflictDoNothingReturning(t *testing.T) {
+	s, vals, err := New(
+		Insert(Just("pizzas"), []string{"name", "price"}, []driver.Value{"margherita", 5}),
+		OnConflictDoNothing(),
+		Returning(Columns("id")),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "INSERT INTO pizzas (name, price)\nVALUES\n\t(?, ?)\nON CONFLICT DO NOTHING\nRETURNING\n\tid\n" {
+		t.Fatalf("wrong DO NOTHING with RETURNING: %q", s)
+	}
+	if len(vals) != 2 || vals[0] != "margherita" || vals[1] != 5 {
+		t.Fatalf("only the inserted values should be bound: %v", vals)
+	}
+}
+
+func TestJSONBAgg(t *testing.T) {
+	s, vals, err := JSONBAgg(
+		JSONBuildObject(
+			JSONField("id", Just("child.id")),
+			JSONField("parent's", Val("name")),
+		),
+		Desc("child.name"),
+		Asc("child.id"),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "JSONB_AGG(jsonb_build_object('id', child.id, 'parent''s', ?) ORDER BY child.name DESC, child.id ASC)" {
+		t.Fatalf("wrong JSONB_AGG: %q", s)
+	}
+	if len(vals) != 1 || vals[0] != "name" {
+		t.Fatalf("wrong values: %v", vals)
+	}
+
+	s, _, err = JSONBAgg(Just("child.name"), NoOp)()
+	if err != nil || s != "JSONB_AGG(child.name)" {
+		t.Fatalf("empty sorting should be dropped: %q %v", s, err)
+	}
+}
+
+func TestScalarInArray(t *testing.T) {
+	tag := "go"
+	s, vals, err := ScalarInArray(&tag, "posts.tags")()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "? = ANY(posts.tags)" || len(vals) != 1 || vals[0] != &tag {
+		t.Fatalf("wrong scalar in array: %q %v", s, vals)
+	}
+
+	var missing *string
+	s, vals, err = ScalarInArray(missing, "posts.tags")()
+	if s != "" || vals != nil || err != nil {
+		t.Fatal("nil value should be a no-op")
+	}
+}
+
+func TestRegex(t *testing.T) {
+	pattern := "^mario.*$"
+	for expected, op := range map[string]SqldFn{
+		"name ~ ?":   Regex("name", &pattern),
+		"name ~* ?":  IRegex("name", &pattern),
+		"name !~ ?":  NotRegex("name", &pattern),
+		"name !~* ?": NotIRegex("name", &pattern),
+	} {
+		s, vals, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 1 || vals[0] != &pattern {
+			t.Fatalf("wrong regex operator: %q %v", s, vals)
+		}
+	}
+
+	if _, _, err := Regex("name", nil)(); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
+
+func TestJSONAccessors(t *testing.T) {
+	for expected, op := range map[string]SqldFn{
+		"data -> ?":  JSONGet("data", "address"),
+		"data ->> ?": JSONGetText("data", "address"),
+	} {
+		s, vals, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 1 || vals[0] != "address" {
+			t.Fatalf("wrong JSON accessor: %q %v", s, vals)
+		}
+	}
+
+	for expected, op := range map[string]SqldFn{
+		"data #> ARRAY[?, ?]::text[]":  JSONPath("data", "address", "city"),
+		"data #>> ARRAY[?, ?]::text[]": JSONPathText("data", "address", "city"),
+	} {
+		s, vals, err := op()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 2 || vals[0] != "address" || vals[1] != "city" {
+			t.Fatalf("wrong JSON path: %q %v", s, vals)
+		}
+	}
+
+	if _, _, err := JSONPath("data")(); !errors.Is(err, ErrEmptySlice) {
+		t.Fatalf("expected empty slice error, got %v", err)
+	}
+
+	s, vals, err := Select(
+		As(JSONGetText("data", "city"), "city"),
+		As(JSONPath("data", "address", "zip"), "zip"),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "SELECT\n\tdata ->> ? AS city,\n\tdata #> ARRAY[?, ?]::text[] AS zip" {
+		t.Fatalf("wrong composed accessors: %q", s)
+	}
+	if len(vals) != 3 || vals[0] != "city" || vals[1] != "address" || vals[2] != "zip" {
+		t.Fatalf("values should follow the columns order: %v", vals)
+	}
+}
+
+func TestArrayContainment(t *testing.T) {
+	tags := []string{"go", "sql", "postgres"}
+	s, vals, err := ArrayContains("post.tags", tags)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "post.tags @> ARRAY[?, ?, ?]" {
+		t.Fatalf("wrong array containment: %q", s)
+	}
+	if len(vals) != 3 || vals[0] != "go" || vals[2] != "postgres" {
+		t.Fatalf("wrong values: %v", vals)
+	}
+
+	for expected, op := range map[string]SqldFn{
+		"post.tags <@ ARRAY[?, ?, ?]": ArrayContainedBy("post.tags", tags),
+		"post.tags && ARRAY[?, ?, ?]": ArrayOverlaps("post.tags", tags),
+	} {
+		if s, _, _ := op(); s != expected {
+			t.Fatalf("wrong array operator: %q", s)
+		}
+	}
+
+	s, vals, err = ArrayContains("post.tags", []string{})()
+	if s != "" || vals != nil || err != nil {
+		t.Fatalf("empty slice should be a no-op, got %q %v %v", s, vals, err)
+	}
+}
+
+func TestEqAny(t *testing.T) {
+	pizzas := []string{"margherita", "diavola", "4 stagioni"}
+
+	in, inVals, err := In("pizzas", &pizzas)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in != "pizzas IN (?, ?, ?)" || len(inVals) != 3 {
+		t.Fatalf("In should bind every value: %q %v", in, inVals)
+	}
+
+	s, vals, err := EqAny("pizzas", pizzas)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "pizzas = ANY(?)" || len(vals) != 1 {
+		t.Fatalf("EqAny should bind a single array: %q %v", s, vals)
+	}
+	if bound, ok := vals[0].([]string); !ok || len(bound) != 3 || bound[0] != "margherita" {
+		t.Fatalf("wrong array value: %v", vals[0])
+	}
+
+	s, vals, err = NeqAll("pizzas", pizzas)()
+	if err != nil || s != "pizzas <> ALL(?)" || len(vals) != 1 {
+		t.Fatalf("wrong NeqAll: %q %v %v", s, vals, err)
+	}
+
+	s, vals, err = EqAny("pizzas", []string{})()
+	if s != "" || vals != nil || err != nil {
+		t.Fatalf("empty slice should be a no-op, got %q %v %v", s, vals, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	status, minTotal := "paid", 10
+	s, vals, err := Select(
+		As(Filter(Count(AllWildcard()), Eq("status", &status)), "paid"),
+		As(Filter(Sum(CoalesceOps(Just("total"), Val(0))), And(Eq("total", &minTotal))), "big"),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SELECT\n\tCOUNT(*) FILTER (WHERE status = ?) AS paid,\n\tSUM(COALESCE(total, ?)) FILTER (WHERE (total = ?\n)) AS big"
+	if s != expected {
+		t.Fatalf("wrong FILTER:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 3 || vals[0] != &status || vals[1] != 0 || vals[2] != &minTotal {
+		t.Fatalf("aggregate values should come before the condition ones: %v", vals)
+	}
+
+	s, _, err = Filter(Count(AllWildcard()), NoOp)()
+	if err != nil || s != "COUNT(*)" {
+		t.Fatalf("empty condition should be dropped: %q %v", s, err)
+	}
+}
+
+func TestStringAgg(t *testing.T) {
+	s, vals, err := StringAgg(Just("name"), ", ", Asc("name"), Desc("price"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "STRING_AGG(name, ? ORDER BY name ASC, price DESC)" || len(vals) != 1 || vals[0] != ", " {
+		t.Fatalf("wrong ordered STRING_AGG: %q %v", s, vals)
+	}
+
+	s, vals, err = StringAgg(Just("name"), ", ")()
+	if err != nil || s != "STRING_AGG(name, ?)" || len(vals) != 1 {
+		t.Fatalf("wrong STRING_AGG: %q %v %v", s, vals, err)
+	}
+
+	if _, _, err = StringAgg(Just("name"), ", ", Sort("sideways", "name"))(); !errors.Is(err, ErrInvalidSortingOrder) {
+		t.Fatalf("expected invalid sorting order error, got %v", err)
+	}
+}
+
+func TestArrayAgg(t *testing.T) {
+	s, vals, err := ArrayAgg(Just("id"), DescNullsLast("created_at"))()
+	if err != nil || s != "ARRAY_AGG(id ORDER BY created_at DESC NULLS LAST)" || len(vals) != 0 {
+		t.Fatalf("wrong ordered ARRAY_AGG: %q %v %v", s, vals, err)
+	}
+
+	s, _, err = ArrayAgg(Just("id"), NoOp)()
+	if err != nil || s != "ARRAY_AGG(id)" {
+		t.Fatalf("empty sorting should be dropped: %q %v", s, err)
+	}
+}
+
+func TestTypedEq(t *testing.T) {
+	id, createdAt := "4b1c3f2e-8d7a-4e0b-9c5f-2a6d8e1f3b7c", "2024-01-01T00:00:00Z"
+	query, args, err := BuildPg(Where(And(
+		TypedEq("id", "uuid", &id),
+		TypedEq("created_at", "timestamptz", &createdAt),
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "WHERE\n\t(id = $1::uuid\nAND created_at = $2::timestamptz\n)\n" {
+		t.Fatalf("cast should survive the placeholders rewriting: %q", query)
+	}
+	if len(args) != 2 || args[0] != &id {
+		t.Fatalf("wrong args: %v", args)
+	}
+
+	if _, _, err := TypedEq[string]("id", "uuid", nil)(); !errors.Is(err, ErrNilVal) {
+		t.Fatalf("expected nil value error, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package sqld_legacy
+
+import "database/sql/driver"
+
+// Query is a chainable alternative to nesting operators in `New()`.
+// Every method appends the corresponding operator, so the clauses are rendered in call order.
+//
+//	query, vals, err := sqld.NewQuery().
+//		Select(sqld.Columns("name", "pizzas")).
+//		From(sqld.Just("Table")).
+//		Where(sqld.Eq("name", filters.Name)).
+//		OrderBy(sqld.Desc(filters.OrderBy)).
+//		Build()
+type Query struct {
+	ops []SqldFn
+}
+
+// NewQuery returns an empty Query
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// Op appends the provided operators, for clauses without a dedicated method
+func (q *Query) Op(ops ...SqldFn) *Query {
+	q.ops = append(q.ops, ops...)
+	return q
+}
+
+// Select appends a `Select()` operator
+func (q *Query) Select(ops ...SqldFn) *Query {
+	return q.Op(Select(ops...))
+}
+
+// From appends a `From()` operator
+func (q *Query) From(op SqldFn) *Query {
+	return q.Op(From(op))
+}
+
+// Join appends a `Join()` operator
+func (q *Query) Join(joinType JoinType, subject SqldFn, op SqldFn) *Query {
+	return q.Op(Join(joinType, subject, op))
+}
+
+// Where appends a `Where()` operator
+func (q *Query) Where(ops ...SqldFn) *Query {
+	return q.Op(Where(ops...))
+}
+
+// GroupBy appends a `GroupBy()` operator
+func (q *Query) GroupBy(ops ...SqldFn) *Query {
+	return q.Op(GroupBy(ops...))
+}
+
+// Having appends a `Having()` operator
+func (q *Query) Having(ops ...SqldFn) *Query {
+	return q.Op(Having(ops...))
+}
+
+// OrderBy appends an `OrderBy()` operator
+func (q *Query) OrderBy(ops ...SqldFn) *Query {
+	return q.Op(OrderBy(ops...))
+}
+
+// Limit appends a `Limit()` operator
+func (q *Query) Limit(count *uint) *Query {
+	return q.Op(Limit(count))
+}
+
+// Offset appends an `Offset()` operator
+func (q *Query) Offset(skip *uint) *Query {
+	return q.Op(Offset(skip))
+}
+
+// SqldFn returns the query as an operator, combining the clauses with `New()`.
+// Use it to nest the query, e.g. in `SubQuery()` or `Exists()`
+func (q *Query) SqldFn() SqldFn {
+	return New(q.ops...)
+}
+
+// Build runs the query
+func (q *Query) Build() (string, []driver.Value, error) {
+	return q.SqldFn()()
+}
@@ -0,0 +1,56 @@
+package sqld_legacy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	name := "mario"
+	limit, offset := uint(10), uint(20)
+
+	fluent, fluentVals, err := NewQuery().
+		Select(Columns("users.name", "count(*)")).
+		From(Just("users")).
+		Join(INNER_JOIN, Just("orders"), ColumnEq("orders.user_id", "users.id")).
+		Where(Eq("users.name", &name)).
+		GroupBy(Columns("users.name")).
+		OrderBy(Desc("users.name")).
+		Limit(&limit).
+		Offset(&offset).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nested, nestedVals, err := New(
+		Select(Columns("users.name", "count(*)")),
+		From(Just("users")),
+		Join(INNER_JOIN, Just("orders"), ColumnEq("orders.user_id", "users.id")),
+		Where(Eq("users.name", &name)),
+		GroupBy(Columns("users.name")),
+		OrderBy(Desc("users.name")),
+		Limit(&limit),
+		Offset(&offset),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fluent != nested {
+		t.Fatalf("fluent query differs from the nested one:\n%q\n%q", fluent, nested)
+	}
+	if !reflect.DeepEqual(fluentVals, nestedVals) {
+		t.Fatalf("fluent values differ from the nested ones: %v %v", fluentVals, nestedVals)
+	}
+}
+
+func TestQuerySubQuery(t *testing.T) {
+	s, _, err := Exists(NewQuery().Select(Just("1")).From(Just("orders")).SqldFn())()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "EXISTS (\nSELECT\n\t1\nFROM orders\n\n)" {
+		t.Fatalf("wrong nested query: %q", s)
+	}
+}
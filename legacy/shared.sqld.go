@@ -0,0 +1,69 @@
+package sqld_legacy
+
+import "database/sql/driver"
+
+// SharedArg is a value bound once and referenced by multiple operators.
+//
+// Every reference renders a `?` placeholder carrying the same *SharedArg;
+// PgPrepareShared collapses all of them into a single `$N` parameter.
+type SharedArg struct {
+	val driver.Value
+}
+
+// Arg registers a value that can be referenced many times in the same query.
+// The returned pointer can be passed to value operators like `Eq()`:
+//
+//	since := sqld.Arg(filters.Since)
+//	sqld.And(
+//		sqld.Compare(sqld.Just("created_at"), ">=", since),
+//		sqld.Compare(sqld.Just("updated_at"), ">=", since),
+//	)
+func Arg[T driver.Value](val T) *SharedArg {
+	return &SharedArg{val: val}
+}
+
+// Value implements driver.Valuer, so queries not prepared with PgPrepareShared
+// still bind the underlying value (once per reference)
+func (a *SharedArg) Value() (driver.Value, error) {
+	return a.val, nil
+}
+
+// Ref builds a callback that just references the shared value
+func (a *SharedArg) Ref() SqldFn {
+	return func() (string, []driver.Value, error) {
+		return "?", []driver.Value{a}, nil
+	}
+}
+
+// PgPrepareShared swaps all ? placeholders with postgres ones ($1, $2...) like PgPrepare,
+// giving every reference to the same SharedArg the same number.
+// The returned values contain each shared value only once.
+func PgPrepareShared(query string, args []driver.Value) (string, []driver.Value) {
+	return Postgres.prepare(query, args)
+}
+
+// prepare rebinds the placeholders for the dialect, binding every SharedArg once for numbered placeholders.
+// With ? placeholders, every reference to a SharedArg binds its value.
+func (d Dialect) prepare(query string, args []driver.Value) (string, []driver.Value) {
+	vals := make([]driver.Value, 0, len(args))
+	shared := make(map[*SharedArg]int)
+
+	query = replacePlaceholders(query, len(args), func(i int) string {
+		sharedArg, ok := args[i].(*SharedArg)
+		if !ok {
+			vals = append(vals, args[i])
+			return d.placeholder(len(vals))
+		}
+
+		n, ok := shared[sharedArg]
+		if !ok || !d.numbered() {
+			vals = append(vals, sharedArg.val)
+			n = len(vals)
+			shared[sharedArg] = n
+		}
+
+		return d.placeholder(n)
+	})
+
+	return query, vals
+}
@@ -0,0 +1,33 @@
+package sqld_legacy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSharedArg(t *testing.T) {
+	since := Arg(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	name := "test"
+
+	query := PgPrepareOp(New(
+		Where(
+			And(
+				Eq("name", &name),
+				Eq("created_at", since),
+				Eq("updated_at", since),
+			),
+		),
+	))
+
+	s, vals, err := query()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "WHERE\n\t(name = $1\nAND created_at = $2\nAND updated_at = $2\n)\n\n" {
+		t.Fatalf("wrong shared placeholders: %q", s)
+	}
+	if len(vals) != 2 || vals[1] != since.val {
+		t.Fatalf("shared value should be bound once: %v", vals)
+	}
+}
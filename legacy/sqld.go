@@ -5,19 +5,36 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 )
 
 var ErrNoColumns = errors.New("no columns in statement")
 var ErrNilVal = errors.New("value is nil")
 var ErrNilColumnExpr = errors.New("column expression is nil")
 var ErrArgNotSlice = errors.New("argument is not a slice")
+var ErrNotStruct = errors.New("argument is not a struct")
 var ErrEmptySlice = errors.New("slice is empty")
 var ErrNoOps = errors.New("operations slice is empty")
+var ErrColumnCountMismatch = errors.New("row length differs from columns count")
+var ErrUnboundedDelete = errors.New("delete without filters")
+var ErrColumnNotAllowed = errors.New("column not allowed")
+var ErrInvalidOperator = errors.New("operator not allowed")
+
+// ErrUnknownOperator is returned by `Custom()` when the operator is not registered for the current dialect
+var ErrUnknownOperator = errors.New("operator not registered")
+var ErrInvalidAlias = errors.New("alias is not a plain identifier")
+var ErrInvalidSortingOrder = errors.New("sorting order is neither ASC nor DESC")
+var ErrColumnNotInModel = errors.New("column not present in model")
+var ErrMissingParam = errors.New("named parameter without value")
+var ErrUnboundPlaceholder = errors.New("placeholder without value")
+var ErrExtraValues = errors.New("value without placeholder")
+var ErrPlaceholderMismatch = errors.New("placeholders and values count differ")
 
 // SqldFn is the type describing all callbacks used in the library.
 type SqldFn func() (string, []driver.Value, error)
 
 // New builds a `SqldFn` callback combining the provided operators.
+// If some of them fail, all their errors are joined and returned together.
 //
 // Example usage:
 //
@@ -47,32 +64,128 @@ func New(ops ...SqldFn) SqldFn {
 			return "", nil, fmt.Errorf("query: %w", ErrNoOps)
 		}
 
-		var sb strings.Builder
-		vals := make([]driver.Value, 0)
-		var errs error
+		statements := make([]string, 0, len(ops))
+		opVals := make([][]driver.Value, 0, len(ops))
+		var errs []error
 
+		// every operator is run, even after a failure, to report all the errors at once
+		size, count := 0, 0
 		for _, fn := range ops {
 			s, fnVals, err := fn()
 			if err != nil {
-				errs = errors.Join(errs, err)
-			}
-
-			if errs != nil {
+				errs = append(errs, err)
 				continue
 			}
 
+			statements = append(statements, s)
+			opVals = append(opVals, fnVals)
+			size += len(s) + 1
+			count += len(fnVals)
+		}
+
+		if len(errs) != 0 {
+			return "", nil, fmt.Errorf("query:\n%w", errors.Join(errs...))
+		}
+
+		var sb strings.Builder
+		sb.Grow(size)
+		vals := make([]driver.Value, 0, count)
+
+		for i, s := range statements {
 			sb.WriteString(s)
 			sb.WriteRune('\n')
 
-			if len(fnVals) != 0 {
-				vals = append(vals, fnVals...)
-			}
+			vals = append(vals, opVals[i]...)
 		}
 
-		if errs != nil {
-			return "", nil, fmt.Errorf("query:\n%w", errs)
+		return sb.String(), vals, nil
+	}
+}
+
+// Script renders the provided operators as a semicolon-separated multi-statement script.
+// Empty operators are skipped, returning `ErrNoOps` if they are all empty.
+//
+// The values of each statement are kept separate, in statement order,
+// since executing multiple statements with bound values varies between drivers.
+func Script(ops ...SqldFn) (string, [][]driver.Value, error) {
+	if len(ops) == 0 {
+		return "", nil, fmt.Errorf("script: %w", ErrNoOps)
+	}
+
+	statements := make([]string, 0, len(ops))
+	vals := make([][]driver.Value, 0, len(ops))
+	var errs error
+
+	for _, fn := range ops {
+		s, fnVals, err := fn()
+		if err != nil {
+			errs = errors.Join(errs, err)
 		}
 
-		return sb.String(), vals, nil
+		if errs != nil || s == "" {
+			continue
+		}
+
+		statements = append(statements, strings.TrimRight(s, "\n"))
+		vals = append(vals, fnVals)
 	}
+
+	if errs != nil {
+		return "", nil, fmt.Errorf("script:\n%w", errs)
+	}
+
+	if len(statements) == 0 {
+		return "", nil, fmt.Errorf("script: %w", ErrNoOps)
+	}
+
+	return strings.Join(statements, ";\n") + ";", vals, nil
+}
+
+// Memoize builds a callback that runs the operator once, returning the cached result on subsequent calls.
+// Use it for static queries executed repeatedly.
+//
+// The query shape is frozen on the first call: the choices of conditionals like `IfNotNil()` and `IfNotEmpty()`,
+// and the placeholders count (and copied elements) of operators like `In()`, are not run again.
+// Values bound as pointers (e.g. by `Eq()` or `Like()`) still follow their variables, since the pointer is cached.
+// The returned values are shared between calls and must not be modified.
+//
+//	var listPizzas = sqld.Memoize(sqld.New(...))
+func Memoize(op SqldFn) SqldFn {
+	var once sync.Once
+	var query string
+	var vals []driver.Value
+	var err error
+
+	return func() (string, []driver.Value, error) {
+		once.Do(func() {
+			query, vals, err = op()
+		})
+
+		return query, vals, err
+	}
+}
+
+// Args converts the values to the []any expected by `database/sql` and `pgx`, one argument for each value.
+// Slice values (e.g. the arrays bound by `EqAny()`) are kept as a single argument.
+func Args(vals []driver.Value) []any {
+	args := make([]any, len(vals))
+	for i, val := range vals {
+		args[i] = val
+	}
+
+	return args
+}
+
+// BuildArgs runs the operator, returning the values as []any, ready for `database/sql`.
+// The placeholders are left as ?: use `Build()` or `BuildPg()` to rebind them.
+//
+//	query, args, err := sqld.BuildArgs(sqld.New(...))
+//	rows, err := db.Query(query, args...)
+func BuildArgs(op SqldFn) (string, []any, error) {
+	query, vals, err := op()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, Args(vals), nil
 }
@@ -1,6 +1,10 @@
 package sqld_legacy
 
 import (
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -67,3 +71,207 @@ func TestSqld(t *testing.T) {
 	}
 	t.Log(s)
 }
+
+func TestScript(t *testing.T) {
+	id, price := 1, 7
+	s, vals, err := Script(
+		Insert(Just("pizzas"), []string{"id", "name"}, []driver.Value{id, "margherita"}),
+		NoOp,
+		New(
+			Update(Just("pizzas"), Set("price", &price)),
+			Where(Eq("id", &id)),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "INSERT INTO pizzas (id, name)\nVALUES\n\t(?, ?);\nUPDATE pizzas\nSET price = ?\nWHERE\n\tid = ?;"
+	if s != expected {
+		t.Fatalf("wrong script:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 2 || len(vals[0]) != 2 || len(vals[1]) != 2 || vals[1][0] != &price {
+		t.Fatalf("values should be kept per statement: %v", vals)
+	}
+
+	if _, _, err = Script(NoOp, Skip()); !errors.Is(err, ErrNoOps) {
+		t.Fatalf("expected no ops error, got %v", err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	_, _, err := New(
+		Select(),
+		From(Just("pizzas")),
+		Where(Eq[string]("name", nil)),
+	)()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !errors.Is(err, ErrNoOps) || !errors.Is(err, ErrNilVal) {
+		t.Fatalf("every failing operator should be reported: %v", err)
+	}
+	if !strings.Contains(err.Error(), "select") || !strings.Contains(err.Error(), "eq (name)") {
+		t.Fatalf("every failing operator should be reported: %v", err)
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	calls := 0
+	name := "margherita"
+	op := New(
+		Select(Columns("id")),
+		From(Just("pizzas")),
+		Where(Eq("name", &name)),
+	)
+	memoized := Memoize(func() (string, []driver.Value, error) {
+		calls++
+		return op()
+	})
+
+	expected, expectedVals, _ := op()
+	for i := 0; i < 3; i++ {
+		s, vals, err := memoized()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected || len(vals) != 1 || vals[0] != expectedVals[0] {
+			t.Fatalf("cached result differs: %q %v", s, vals)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("operator should run once, ran %d times", calls)
+	}
+
+	_, _, err := Memoize(Select())()
+	if !errors.Is(err, ErrNoOps) {
+		t.Fatalf("errors should be cached too, got %v", err)
+	}
+}
+
+func TestMemoizeBoundValues(t *testing.T) {
+	name := "margherita"
+	var price *int
+	pizzas := []string{"margherita"}
+	memoized := Memoize(Where(And(
+		Eq("name", &name),
+		IfNotNil(price, Eq("price", price)),
+		In("pizzas", &pizzas),
+	)))
+
+	s, _, err := memoized()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name = "diavola"
+	price = new(int)
+	pizzas = append(pizzas, "diavola")
+
+	s2, vals, err := memoized()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2 != s || s != "WHERE\n\t(name = ?\nAND pizzas IN (?)\n)\n" {
+		t.Fatalf("the query shape should be frozen: %q", s2)
+	}
+	if len(vals) != 2 || *vals[0].(*string) != "diavola" || vals[1] != "margherita" {
+		t.Fatalf("pointer values should follow their variables, copied ones should be frozen: %v", vals)
+	}
+}
+
+func benchmarkQuery() SqldFn {
+	name := "margherita"
+	pizzas := []string{"margherita", "diavola", "4 stagioni"}
+
+	return New(
+		Select(Columns("id", "name", "price")),
+		From(Just("pizzas")),
+		Where(And(
+			Eq("name", &name),
+			In("pizzas", &pizzas),
+		)),
+		OrderBy(Desc("price")),
+	)
+}
+
+func BenchmarkQuery(b *testing.B) {
+	op := benchmarkQuery()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := op(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMemoize(b *testing.B) {
+	op := Memoize(benchmarkQuery())
+	for i := 0; i < b.N; i++ {
+		if _, _, err := op(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLargeQuery(b *testing.B) {
+	ids := make([]int, 100)
+	conds := make([]SqldFn, 0, len(ids))
+	for i := range ids {
+		ids[i] = i
+		conds = append(conds, Eq("id", &ids[i]))
+	}
+
+	op := New(
+		Select(Columns("id", "name", "price")),
+		From(Just("pizzas")),
+		Where(Or(conds...)),
+		GroupBy(Columns("id", "name", "price")),
+		OrderBy(Desc("price")),
+	)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := op(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	name := "margherita"
+	vals := []driver.Value{1, "diavola", &name, nil, []driver.Value{2, 3}}
+
+	args := Args(vals)
+	if len(args) != 5 {
+		t.Fatalf("there should be an arg for each value: %v", args)
+	}
+	if args[0] != 1 || args[1] != "diavola" || args[2] != &name || args[3] != nil || !slices.Equal(args[4].([]driver.Value), vals[4].([]driver.Value)) {
+		t.Fatalf("values should be kept as they are: %v", args)
+	}
+
+	query, args, err := BuildArgs(New(Select(Columns("id")), Where(Eq("name", &name))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "SELECT\n\tid\nWHERE\n\tname = ?\n\n" || len(args) != 1 || args[0] != &name {
+		t.Fatalf("wrong built query: %q %v", query, args)
+	}
+}
+
+func TestArgsArrays(t *testing.T) {
+	query, args, err := BuildPg(Where(EqAny("id", []driver.Value{1, 2, 3})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "WHERE\n\tid = ANY($1)\n" || len(args) != 1 {
+		t.Fatalf("array should be bound as a single arg: %q %v", query, args)
+	}
+
+	query, args, err = BuildArgs(Val([]driver.Value{"a", "b"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CountPlaceholders(query) != len(args) {
+		t.Fatalf("there should be an arg for each placeholder: %q %v", query, args)
+	}
+}
@@ -1,6 +1,10 @@
 package sqld_legacy
 
-import "database/sql/driver"
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
 
 func mapSlice[T driver.Value](vals []T) []driver.Value {
 	mappedVals := make([]driver.Value, 0, len(vals))
@@ -10,3 +14,114 @@ func mapSlice[T driver.Value](vals []T) []driver.Value {
 
 	return mappedVals
 }
+
+// runOps runs all the operators, collecting the non-empty results and the errors.
+// It's the first pass of the combining operators: the returned length of the results and the values capacity
+// are exact, so that the final query can be built without reallocations
+func runOps(ops []SqldFn) (parts []string, size int, vals []driver.Value, errs error) {
+	parts = make([]string, 0, len(ops))
+	opVals := make([][]driver.Value, 0, len(ops))
+	count := 0
+
+	// every operator is run, even after a failure, to report all the errors at once
+	for _, fn := range ops {
+		s, fnVals, err := fn()
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		if errs != nil || s == "" {
+			continue
+		}
+
+		parts = append(parts, s)
+		opVals = append(opVals, fnVals)
+		size += len(s)
+		count += len(fnVals)
+	}
+
+	if errs != nil {
+		return nil, 0, nil, errs
+	}
+
+	vals = make([]driver.Value, 0, count)
+	for _, fnVals := range opVals {
+		vals = append(vals, fnVals...)
+	}
+
+	return parts, size, vals, nil
+}
+
+// joinParts concatenates the parts with the separator, between prefix and suffix, with a single allocation
+func joinParts(prefix string, parts []string, size int, sep string, suffix string) string {
+	var sb strings.Builder
+	sb.Grow(len(prefix) + size + len(sep)*(len(parts)-1) + len(suffix))
+
+	sb.WriteString(prefix)
+	for i, part := range parts {
+		if i != 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(part)
+	}
+	sb.WriteString(suffix)
+
+	return sb.String()
+}
+
+// placeholders returns n comma-separated `?` placeholders
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	return strings.Repeat(", ?", n)[2:]
+}
+
+// EscapeLikePattern escapes the LIKE wildcards (and the escapeChar itself) in the given string,
+// so that they are matched literally
+func EscapeLikePattern(s string, escapeChar rune) string {
+	esc := string(escapeChar)
+	return strings.NewReplacer(esc, esc+esc, "%", esc+"%", "_", esc+"_").Replace(s)
+}
+
+// isIdentifier reports whether s is a plain SQL identifier, safe to be interpolated unquoted
+func isIdentifier(s string) bool {
+	if s == "" || !isNameChar(s[0], true) {
+		return false
+	}
+
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i], false) && s[i] != '$' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isAlias reports whether s is a plain identifier, optionally followed by a column aliases list
+// (e.g. `t(id, name)`), safe to be interpolated unquoted
+func isAlias(s string) bool {
+	name, columns, hasColumns := strings.Cut(s, "(")
+	if !isIdentifier(name) {
+		return false
+	}
+	if !hasColumns {
+		return true
+	}
+
+	columns, ok := strings.CutSuffix(columns, ")")
+	if !ok {
+		return false
+	}
+
+	for _, column := range strings.Split(columns, ",") {
+		if !isIdentifier(strings.TrimSpace(column)) {
+			return false
+		}
+	}
+
+	return true
+}
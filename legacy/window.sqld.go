@@ -0,0 +1,134 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Over builds a callback that applies the window function over the given partition and ordering.
+// Empty partition and ordering are omitted.
+//
+//	sqld.Over(
+//		sqld.Lag(sqld.Just("amount"), 1, sqld.Just("0")),
+//		[]string{"customer_id"},
+//		sqld.Asc("created_at"),
+//	)
+func Over(fn SqldFn, partition []string, order ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := fn()
+		if err != nil {
+			return "", nil, fmt.Errorf("over: %w", err)
+		}
+
+		window, windowVals, err := windowSpec(partition, order...)
+		if err != nil {
+			return "", nil, fmt.Errorf("over: %w", err)
+		}
+
+		return s + " OVER (" + window + ")", append(vals, windowVals...), nil
+	}
+}
+
+// OverFrame is like `Over()`, restricting the window to the provided frame.
+//
+//	sqld.OverFrame(
+//		sqld.Avg(sqld.Just("amount")),
+//		"ROWS BETWEEN 6 PRECEDING AND CURRENT ROW",
+//		nil,
+//		sqld.Asc("day"),
+//	)
+func OverFrame(fn SqldFn, frame string, partition []string, order ...SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := fn()
+		if err != nil {
+			return "", nil, fmt.Errorf("over: %w", err)
+		}
+
+		window, windowVals, err := windowSpec(partition, order...)
+		if err != nil {
+			return "", nil, fmt.Errorf("over: %w", err)
+		}
+
+		if window != "" {
+			window += " "
+		}
+
+		return s + " OVER (" + window + frame + ")", append(vals, windowVals...), nil
+	}
+}
+
+// RunningSum builds a callback that returns the running total of the expression,
+// over the given partition and ordering.
+//
+//	sqld.RunningSum(sqld.Just("amount"), []string{"customer_id"}, sqld.Asc("created_at"))
+func RunningSum(expr SqldFn, partition []string, order ...SqldFn) SqldFn {
+	return OverFrame(Sum(expr), "ROWS UNBOUNDED PRECEDING", partition, order...)
+}
+
+// windowSpec renders the PARTITION BY and ORDER BY parts of a window definition
+func windowSpec(partition []string, order ...SqldFn) (string, []driver.Value, error) {
+	parts := make([]string, 0, 2)
+	if len(partition) != 0 {
+		parts = append(parts, "PARTITION BY "+strings.Join(partition, ", "))
+	}
+
+	sorts, vals := make([]string, 0, len(order)), make([]driver.Value, 0)
+	for _, op := range order {
+		s, opVals, err := op()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if s == "" {
+			continue
+		}
+
+		sorts = append(sorts, s)
+
+		if len(opVals) != 0 {
+			vals = append(vals, opVals...)
+		}
+	}
+	if len(sorts) != 0 {
+		parts = append(parts, "ORDER BY "+strings.Join(sorts, ", "))
+	}
+
+	return strings.Join(parts, " "), vals, nil
+}
+
+func offsetFn(name string, op SqldFn, offset int, dflt SqldFn) SqldFn {
+	return func() (string, []driver.Value, error) {
+		s, vals, err := op()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		d, dfltVals, err := dflt()
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		args := s + ", " + strconv.Itoa(offset)
+		if d != "" {
+			args += ", " + d
+		}
+
+		return name + "(" + args + ")", append(vals, dfltVals...), nil
+	}
+}
+
+// Lag builds a callback that returns the expression evaluated at the row that is
+// offset rows before the current one, falling back on the default (if not empty).
+// Use it with `Over()`.
+func Lag(op SqldFn, offset int, dflt SqldFn) SqldFn {
+	return offsetFn("lag", op, offset, dflt)
+}
+
+// Lead builds a callback that returns the expression evaluated at the row that is
+// offset rows after the current one, falling back on the default (if not empty).
+// Use it with `Over()`.
+func Lead(op SqldFn, offset int, dflt SqldFn) SqldFn {
+	return offsetFn("lead", op, offset, dflt)
+}
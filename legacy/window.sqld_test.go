@@ -0,0 +1,60 @@
+package sqld_legacy
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestLagLead(t *testing.T) {
+	s, vals, err := Over(
+		Lag(Just("amount"), 1, Val(0)),
+		[]string{"customer_id"},
+		Asc("created_at"),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "lag(amount, 1, ?) OVER (PARTITION BY customer_id ORDER BY created_at ASC)" {
+		t.Fatalf("wrong LAG: %q", s)
+	}
+	if len(vals) != 1 || vals[0] != driver.Value(0) {
+		t.Fatalf("wrong values: %v", vals)
+	}
+
+	s, _, err = Over(Lead(Just("amount"), 2, NoOp), nil)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "lead(amount, 2) OVER ()" {
+		t.Fatalf("wrong LEAD: %q", s)
+	}
+}
+
+func TestRunningSum(t *testing.T) {
+	s, vals, err := RunningSum(
+		CoalesceOps(Just("amount"), Val(0)),
+		[]string{"customer_id"},
+		Asc("created_at"),
+		Sort(DESC, "id"),
+	)()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SUM(COALESCE(amount, ?)) OVER (PARTITION BY customer_id ORDER BY created_at ASC, id DESC ROWS UNBOUNDED PRECEDING)"
+	if s != expected {
+		t.Fatalf("wrong running sum:\n%q\n%q", s, expected)
+	}
+	if len(vals) != 1 || vals[0] != driver.Value(0) {
+		t.Fatalf("wrong values: %v", vals)
+	}
+
+	s, _, err = OverFrame(Avg(Just("amount")), "ROWS BETWEEN 6 PRECEDING AND CURRENT ROW", nil)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "AVG(amount) OVER (ROWS BETWEEN 6 PRECEDING AND CURRENT ROW)" {
+		t.Fatalf("wrong frame without window: %q", s)
+	}
+}
@@ -1,11 +1,21 @@
 package sqld
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 )
 
+var (
+	// ErrMissingParam is returned by RebindNamed and FragmentErr when a named parameter is missing
+	ErrMissingParam = errors.New("named parameter without value")
+	// ErrEmptySlice is returned by RebindNamed when a slice parameter has no elements to expand
+	ErrEmptySlice = errors.New("empty slice parameter")
+)
+
 // Op is a boolean operator
 type Op string
 
@@ -150,6 +160,20 @@ func Eq(target string) PrinterFn {
 	}
 }
 
+// DistinctFrom produces a PrinterFn that checks if the target is distinct from the parameter, treating NULLs as comparable
+func DistinctFrom(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s IS DISTINCT FROM :%s", target, param)
+	}
+}
+
+// NotDistinctFrom produces a PrinterFn that checks if the target is not distinct from the parameter, treating NULLs as comparable
+func NotDistinctFrom(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s IS NOT DISTINCT FROM :%s", target, param)
+	}
+}
+
 // Like produces a PrinterFn that checks if the target text respects the given pattern
 func Like(target string) PrinterFn {
 	return func(param string) string {
@@ -164,6 +188,80 @@ func ILike(target string) PrinterFn {
 	}
 }
 
+// LikeEscape produces a PrinterFn that checks if the target text respects the given pattern,
+// using escapeChar to escape the wildcards. Use EscapeLikePattern to escape the parameter
+func LikeEscape(target string, escapeChar rune) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s LIKE :%s ESCAPE '%s'", target, param, strings.ReplaceAll(string(escapeChar), "'", "''"))
+	}
+}
+
+// EscapeLikePattern escapes the LIKE wildcards (and the escapeChar itself) in the given string,
+// so that they are matched literally
+func EscapeLikePattern(s string, escapeChar rune) string {
+	esc := string(escapeChar)
+	return strings.NewReplacer(esc, esc+esc, "%", esc+"%", "_", esc+"_").Replace(s)
+}
+
+// PgRegex produces a PrinterFn that checks if the target text matches the given POSIX regex (Postgres only)
+func PgRegex(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s ~ :%s", target, param)
+	}
+}
+
+// PgIRegex produces a PrinterFn that checks if the target text matches the given POSIX regex,
+// ignoring the casing (Postgres only)
+func PgIRegex(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s ~* :%s", target, param)
+	}
+}
+
+// PgNotRegex produces a PrinterFn that checks if the target text doesn't match the given POSIX regex (Postgres only)
+func PgNotRegex(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s !~ :%s", target, param)
+	}
+}
+
+// PgNotIRegex produces a PrinterFn that checks if the target text doesn't match the given POSIX regex,
+// ignoring the casing (Postgres only)
+func PgNotIRegex(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s !~* :%s", target, param)
+	}
+}
+
+// pgTsQuery returns the text search vector of the column and the query of the parameter,
+// with the given text search configuration (`simple` if empty)
+func pgTsQuery(column, config, param string) (string, string) {
+	if config == "" {
+		config = "simple"
+	}
+	config = "'" + strings.ReplaceAll(config, "'", "''") + "'"
+
+	return fmt.Sprintf("to_tsvector(%s, %s)", config, column), fmt.Sprintf("plainto_tsquery(%s, :%s)", config, param)
+}
+
+// PgFullText produces a PrinterFn that checks if the column text matches the given full-text search query,
+// with the given text search configuration (`simple` if empty) (Postgres only)
+func PgFullText(column, config string) PrinterFn {
+	return func(param string) string {
+		vector, query := pgTsQuery(column, config, param)
+		return vector + " @@ " + query
+	}
+}
+
+// PgFullTextRank produces a PrinterFn that ranks the column text against the given full-text search query,
+// with the given text search configuration (`simple` if empty) (Postgres only)
+func PgFullTextRank(column, config string) PrinterFn {
+	return func(param string) string {
+		vector, query := pgTsQuery(column, config, param)
+		return fmt.Sprintf("ts_rank(%s, %s)", vector, query)
+	}
+}
+
 // In produces a PrinterFn that checks if the target is contained in the given parameter slice
 func In(target string) PrinterFn {
 	return func(param string) string {
@@ -171,6 +269,13 @@ func In(target string) PrinterFn {
 	}
 }
 
+// NotIn produces a PrinterFn that checks if the target is not contained in the given parameter slice
+func NotIn(target string) PrinterFn {
+	return func(param string) string {
+		return fmt.Sprintf("%s NOT IN(:%s)", target, param)
+	}
+}
+
 // Gt produces a PrinterFn that checks if the target is greater than the given parameter
 func Gt(target string) PrinterFn {
 	return func(param string) string {
@@ -277,6 +382,31 @@ func If[T any](pred PredicateFn[T], val T, params *Params, printer PrinterFn) st
 		return ""
 	}
 
+	return bindParam(val, params, printer)
+}
+
+// IfElse is like If, with a printer for each outcome of the predicate.
+// The value is pushed in the parameter map only once, for the chosen printer.
+func IfElse[T any](pred PredicateFn[T], val T, params *Params, trueP, falseP PrinterFn) string {
+	if pred(val) {
+		return bindParam(val, params, trueP)
+	}
+
+	return bindParam(val, params, falseP)
+}
+
+// IfMap is like If, mapping the value before pushing it in the parameter map (e.g. with FmtContains).
+// The predicate is checked on the original value, and the mapping is skipped if it's false.
+func IfMap[T any](pred PredicateFn[T], val T, mapFn func(T) T, params *Params, printer PrinterFn) string {
+	if !pred(val) {
+		return ""
+	}
+
+	return bindParam(mapFn(val), params, printer)
+}
+
+// bindParam pushes the value in the parameter map with a generated name, and prints the filter with it
+func bindParam(val any, params *Params, printer PrinterFn) string {
 	argName := "arg" + strconv.Itoa(len(*params))
 	(*params)[argName] = val
 
@@ -304,3 +434,137 @@ func IfNotEmpty[T any](val []T, params *Params, printer PrinterFn) string {
 		return len(t) > 0
 	}, val, params, printer)
 }
+
+// RebindNamed swaps the named parameters (:name) of the query with ? placeholders, for MySQL and SQLite,
+// returning their values in order of appearance (once for each occurrence).
+// Postgres casts (::type) and names inside quotes are left untouched.
+// Slice parameters (e.g. from In and NotIn) are expanded to a placeholder for each element, like sqlx.In does.
+// Returns ErrMissingParam if a parameter has no value, and ErrEmptySlice if a slice parameter is empty.
+func RebindNamed(query string, params Params) (string, []any, error) {
+	args := make([]any, 0, len(params))
+
+	query, err := replaceNamed(query, func(name string) (string, error) {
+		val, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("rebind named (%s): %w", name, ErrMissingParam)
+		}
+
+		elems, ok := expandSlice(val)
+		if !ok {
+			args = append(args, val)
+			return "?", nil
+		}
+
+		if len(elems) == 0 {
+			return "", fmt.Errorf("rebind named (%s): %w", name, ErrEmptySlice)
+		}
+
+		args = append(args, elems...)
+		return strings.Repeat("?, ", len(elems)-1) + "?", nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, args, nil
+}
+
+// expandSlice returns the elements of a slice or array parameter (or a pointer to one).
+// Byte slices and driver.Valuer values are bound as they are
+func expandSlice(val any) ([]any, bool) {
+	if _, ok := val.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems := make([]any, v.Len())
+	for i := range elems {
+		elems[i] = v.Index(i).Interface()
+	}
+
+	return elems, true
+}
+
+// Fragment returns a hand-written fragment with named parameters (e.g. from an existing sqlx.Named query),
+// to be composed with And, Or, Where... checking that all the required parameters appear in it.
+// Panics if a required parameter is missing: use FragmentErr to get an error instead.
+func Fragment(sql string, required ...string) string {
+	fragment, err := FragmentErr(sql, required...)
+	if err != nil {
+		panic(err)
+	}
+
+	return fragment
+}
+
+// FragmentErr is like Fragment, returning ErrMissingParam if a required parameter is missing
+func FragmentErr(sql string, required ...string) (string, error) {
+	found := make(map[string]bool, len(required))
+	_, _ = replaceNamed(sql, func(name string) (string, error) {
+		found[name] = true
+		return ":" + name, nil
+	})
+
+	for _, name := range required {
+		if !found[name] {
+			return "", fmt.Errorf("fragment (%s): %w", name, ErrMissingParam)
+		}
+	}
+
+	return sql, nil
+}
+
+// replaceNamed replaces the named parameters (:name) of the query with the result of the callback.
+// Postgres casts (::type) and names inside quotes are left untouched.
+func replaceNamed(query string, replace func(name string) (string, error)) (string, error) {
+	bldr := strings.Builder{}
+	bldr.Grow(len(query))
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if c == '\'' || c == '"' {
+			end := strings.IndexByte(query[i+1:], c)
+			if end == -1 {
+				bldr.WriteString(query[i:])
+				break
+			}
+
+			bldr.WriteString(query[i : i+end+2])
+			i += end + 1
+			continue
+		}
+
+		if c != ':' || i+1 == len(query) || !isParamChar(query[i+1], true) || i > 0 && query[i-1] == ':' {
+			bldr.WriteByte(c)
+			continue
+		}
+
+		end := i + 1
+		for end < len(query) && isParamChar(query[end], false) {
+			end++
+		}
+
+		replacement, err := replace(query[i+1 : end])
+		if err != nil {
+			return "", err
+		}
+
+		bldr.WriteString(replacement)
+		i = end - 1
+	}
+
+	return bldr.String(), nil
+}
+
+func isParamChar(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
@@ -0,0 +1,205 @@
+package sqld
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNotIn(t *testing.T) {
+	if s := NotIn("pizzas")("arg0"); s != "pizzas NOT IN(:arg0)" {
+		t.Fatalf("wrong NOT IN: %s", s)
+	}
+
+	params := make(Params)
+	if s := IfNotEmpty([]string{}, &params, NotIn("pizzas")); s != "" || len(params) != 0 {
+		t.Fatalf("empty slice should be skipped: %s", s)
+	}
+}
+
+func TestLikeEscape(t *testing.T) {
+	if s := LikeEscape("name", '\\')("arg0"); s != `name LIKE :arg0 ESCAPE '\'` {
+		t.Fatalf("wrong LIKE ESCAPE: %s", s)
+	}
+	if s := LikeEscape("name", '\'')("arg0"); s != `name LIKE :arg0 ESCAPE ''''` {
+		t.Fatalf("wrong quoted LIKE ESCAPE: %s", s)
+	}
+
+	params := make(Params)
+	input := `50%_off\`
+	IfNotZero(FmtContains(EscapeLikePattern(input, '\\')), &params, LikeEscape("name", '\\'))
+	if params["arg0"] != `%50\%\_off\\%` {
+		t.Fatalf("wildcards in user input should be neutralized: %v", params["arg0"])
+	}
+}
+
+func TestPgRegex(t *testing.T) {
+	for expected, printer := range map[string]PrinterFn{
+		"name ~ :arg0":   PgRegex("name"),
+		"name ~* :arg0":  PgIRegex("name"),
+		"name !~ :arg0":  PgNotRegex("name"),
+		"name !~* :arg0": PgNotIRegex("name"),
+	} {
+		params := make(Params)
+		if s := IfNotZero("^mario.*$", &params, printer); s != expected {
+			t.Fatalf("wrong regex operator: %s", s)
+		}
+		if params["arg0"] != "^mario.*$" {
+			t.Fatalf("pattern should be parameterized: %v", params)
+		}
+	}
+}
+
+func TestDistinctFrom(t *testing.T) {
+	if s := DistinctFrom("manager_id")("arg0"); s != "manager_id IS DISTINCT FROM :arg0" {
+		t.Fatalf("wrong IS DISTINCT FROM: %s", s)
+	}
+
+	var managerID *int
+	params := make(Params)
+	always := func(*int) bool { return true }
+	if s := If(always, managerID, &params, NotDistinctFrom("manager_id")); s != "manager_id IS NOT DISTINCT FROM :arg0" {
+		t.Fatalf("wrong IS NOT DISTINCT FROM: %s", s)
+	}
+	if v, ok := params["arg0"]; !ok || v.(*int) != nil {
+		t.Fatalf("nil value should be bound as NULL: %v", params)
+	}
+}
+
+func TestPgFullText(t *testing.T) {
+	if s := PgFullText("body", "english")("arg0"); s != "to_tsvector('english', body) @@ plainto_tsquery('english', :arg0)" {
+		t.Fatalf("wrong full-text match: %s", s)
+	}
+	if s := PgFullText("body", "")("arg0"); s != "to_tsvector('simple', body) @@ plainto_tsquery('simple', :arg0)" {
+		t.Fatalf("config should default to simple: %s", s)
+	}
+
+	params := make(Params)
+	rank := IfNotZero("pizza napoletana", &params, PgFullTextRank("body", "italian"))
+	if rank != "ts_rank(to_tsvector('italian', body), plainto_tsquery('italian', :arg0))" {
+		t.Fatalf("wrong full-text rank: %s", rank)
+	}
+	if s := OrderBy(Desc(rank)); s != "\nORDER BY "+rank+" DESC" {
+		t.Fatalf("rank should be sortable: %s", s)
+	}
+	if params["arg0"] != "pizza napoletana" {
+		t.Fatalf("query should be parameterized: %v", params)
+	}
+}
+
+func TestIfElse(t *testing.T) {
+	isPattern := func(name string) bool {
+		return strings.ContainsAny(name, "%_")
+	}
+
+	params := Params{}
+	filter := IfElse(isPattern, "marg%", &params, Like("name"), Eq("name"))
+	if filter != "name LIKE :arg0" {
+		t.Fatalf("true branch should be printed: %s", filter)
+	}
+
+	filter = IfElse(isPattern, "diavola", &params, Like("name"), Eq("name"))
+	if filter != "name = :arg1" {
+		t.Fatalf("false branch should be printed: %s", filter)
+	}
+
+	if len(params) != 2 || params["arg0"] != "marg%" || params["arg1"] != "diavola" {
+		t.Fatalf("only the chosen branches should register a param: %v", params)
+	}
+}
+
+func TestIfMap(t *testing.T) {
+	notEmpty := func(name string) bool {
+		return name != ""
+	}
+
+	params := Params{}
+	filter := And(
+		IfMap(notEmpty, "marg", FmtContains, &params, Like("name")),
+		IfMap(notEmpty, "", FmtContains, &params, Like("nickname")),
+	)
+	if filter != "(\n\tname LIKE :arg0\n)" {
+		t.Fatalf("only the true predicate should be printed: %q", filter)
+	}
+	if len(params) != 1 || params["arg0"] != "%marg%" {
+		t.Fatalf("the mapped value should be registered: %v", params)
+	}
+}
+
+func TestRebindNamed(t *testing.T) {
+	params := Params{"arg0": "mario", "arg1": 5}
+	query, args, err := RebindNamed("name = :arg0 AND (price > :arg1 OR nickname = :arg0)", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "name = ? AND (price > ? OR nickname = ?)" {
+		t.Fatalf("wrong rebound query: %s", query)
+	}
+	if len(args) != 3 || args[0] != "mario" || args[1] != 5 || args[2] != "mario" {
+		t.Fatalf("args should follow the parameters appearance: %v", args)
+	}
+
+	query, args, err = RebindNamed(`label = ':arg1' AND "col:arg1" = :arg1 AND created_at::date = :arg0`, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != `label = ':arg1' AND "col:arg1" = ? AND created_at::date = ?` {
+		t.Fatalf("quoted names and casts should be untouched: %s", query)
+	}
+	if len(args) != 2 || args[0] != 5 || args[1] != "mario" {
+		t.Fatalf("wrong args: %v", args)
+	}
+
+	if _, _, err := RebindNamed("name = :missing", params); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("expected missing param error, got %v", err)
+	}
+}
+
+func TestRebindNamedSlice(t *testing.T) {
+	params := Params{}
+	filter := And(
+		IfNotEmpty([]int{1, 2, 3}, &params, In("id")),
+		IfNotEmpty([]string{"diavola"}, &params, NotIn("name")),
+	)
+
+	query, args, err := RebindNamed(filter, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "(\n\tid IN(?, ?, ?) AND\n\tname NOT IN(?)\n)" {
+		t.Fatalf("slice params should be expanded: %q", query)
+	}
+	if len(args) != 4 || args[0] != 1 || args[1] != 2 || args[2] != 3 || args[3] != "diavola" {
+		t.Fatalf("slice elements should be bound one by one: %v", args)
+	}
+
+	query, args, err = RebindNamed("data = :arg0", Params{"arg0": []byte("raw")})
+	if err != nil || query != "data = ?" || len(args) != 1 {
+		t.Fatalf("byte slices should be bound as they are: %q %v %v", query, args, err)
+	}
+
+	if _, _, err := RebindNamed("id IN(:arg0)", Params{"arg0": []int{}}); !errors.Is(err, ErrEmptySlice) {
+		t.Fatalf("expected empty slice error, got %v", err)
+	}
+}
+
+func TestFragment(t *testing.T) {
+	params := Params{"min": 5, "max": 10}
+	fragment := Fragment("price BETWEEN :min AND :max", "min", "max")
+
+	query := Where(And(fragment, IfNotZero("mario", &params, Eq("name"))))
+	if query != "\nWHERE (\n\tprice BETWEEN :min AND :max AND\n\tname = :arg2\n)" {
+		t.Fatalf("fragment should be composable: %q", query)
+	}
+
+	if _, err := FragmentErr("price > :min AND label = ':max'", "min", "max"); !errors.Is(err, ErrMissingParam) {
+		t.Fatalf("expected missing param error, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	Fragment("price > :min", "min", "max")
+}